@@ -2,103 +2,385 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
 	"github.com/obezpalko/helm-repo-exporter/internal/fetcher"
+	"github.com/obezpalko/helm-repo-exporter/internal/indexer"
+	"github.com/obezpalko/helm-repo-exporter/internal/logging"
 	"github.com/obezpalko/helm-repo-exporter/internal/metrics"
+	"github.com/obezpalko/helm-repo-exporter/internal/notifier"
+	"github.com/obezpalko/helm-repo-exporter/internal/probe"
+	"github.com/obezpalko/helm-repo-exporter/internal/provenance"
+	"github.com/obezpalko/helm-repo-exporter/internal/telemetry"
 	"github.com/obezpalko/helm-repo-exporter/internal/web"
 	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
 )
 
+// repoClient bundles everything needed to scrape a single repository
+type repoClient struct {
+	client   *fetcher.Client
+	repo     config.Repository
+	interval time.Duration
+	verifier *provenance.Verifier // nil when provenance verification is disabled
+	linter   *analyzer.Linter
+	charts   *indexer.Generator // nil when index generation is disabled
+	filter   *analyzer.ChartFilter
+	log      *slog.Logger // Carries the repository name and URL
+}
+
+// exporter holds the state shared by all repository scrapes
+type exporter struct {
+	repoClients      []*repoClient
+	metricsCollector *metrics.Metrics
+	htmlGenerator    *web.HTMLGenerator
+	graphHandler     *web.DependencyGraphHandler
+	feedHandler      *web.FeedHandler
+	badgeHandler     *web.BadgeHandler
+	indexProxy       *web.IndexProxyHandler // nil when no index groups are configured
+	notifier         *notifier.Notifier     // nil when notifications are disabled
+	versions         *analyzer.VersionTracker
+
+	mu        sync.RWMutex
+	analyses  map[string]*analyzer.ChartAnalysis // Latest successful analysis per repository
+	scrapedAt map[string]time.Time               // Start of the latest scrape per repository
+
+	scrapes singleflight.Group // De-duplicates concurrent scrapes of a repository
+}
+
+// Set at build time with -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// buildCommit returns the commit set at build time, falling back to the VCS revision
+// recorded by the Go toolchain
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
 func main() {
-	log.Println("Starting Helm Repository Exporter...")
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		if err := runReindex(os.Args[2:]); err != nil {
+			fatal("Reindex failed", logging.ErrorReason(err))
+		}
+		return
+	}
 
 	// Load configuration
 	cfg, err := config.LoadFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", logging.ErrorReason(err))
 	}
 
-	log.Printf("Configuration loaded:")
-	log.Printf("  Repositories: %d", len(cfg.Repositories))
+	// Route every log record, including those of the standard log package, through the redacting handler
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level, cfg.Secrets())
+	if err != nil {
+		fatal("Invalid log configuration", logging.ErrorReason(err))
+	}
+	slog.SetDefault(logger)
+
+	slog.Info("Starting Helm Repository Exporter", "version", version, "commit", buildCommit())
+	slog.Info("Configuration loaded",
+		"repositories", len(cfg.Repositories),
+		"scan_interval", cfg.ScanInterval,
+		"scan_timeout", cfg.ScanTimeout,
+		"collect_on_scrape", cfg.CollectOnScrape,
+		"metrics_port", cfg.MetricsPort,
+		"html", cfg.EnableHTML,
+	)
 	for _, repo := range cfg.Repositories {
-		log.Printf("    - %s: %s (interval: %v)", repo.Name, repo.URL, repo.ScanInterval)
+		attrs := []any{logging.KeyRepository, repo.Name, logging.KeyURL, repo.URL, "interval", repo.ScanInterval}
 		if repo.Auth != nil {
 			switch {
 			case repo.Auth.Basic != nil:
-				log.Printf("      Auth: Basic (username: %s)", repo.Auth.Basic.Username)
+				attrs = append(attrs, "auth", "basic")
 			case repo.Auth.BearerToken != "":
-				log.Printf("      Auth: Bearer Token")
+				attrs = append(attrs, "auth", "bearer")
 			case len(repo.Auth.Headers) > 0:
-				log.Printf("      Auth: Custom Headers (%d)", len(repo.Auth.Headers))
+				attrs = append(attrs, "auth", "headers")
 			}
 		}
+		if repo.TLS != nil {
+			attrs = append(attrs, "tls_insecure", repo.TLS.InsecureSkipVerify)
+		}
+		if repo.Provenance != nil {
+			attrs = append(attrs, "keyring", repo.Provenance.Keyring)
+		}
+		if repo.MirrorOf != "" {
+			attrs = append(attrs, "mirror_of", repo.MirrorOf)
+		}
+		if repo.Watch {
+			attrs = append(attrs, "watch", true)
+		}
+		if repo.GenerateIndex != nil {
+			attrs = append(attrs, "generate_index", repo.GenerateIndex.When, "generate_source", repo.GenerateIndex.Source)
+		}
+		slog.Info("Repository configured", attrs...)
 	}
-	log.Printf("  Default Scan Interval: %v", cfg.ScanInterval)
-	log.Printf("  Scan Timeout: %v", cfg.ScanTimeout)
-	log.Printf("  Metrics Port: %s", cfg.MetricsPort)
-	log.Printf("  Enable HTML: %v", cfg.EnableHTML)
 
 	// Create HTTP clients for each repository
 	ctx := context.Background()
-	type repoClient struct {
-		client   *fetcher.Client
-		interval time.Duration
-	}
-	var repoClients []repoClient
+	var repoClients []*repoClient
 	for _, repo := range cfg.Repositories {
 		client := fetcher.NewClient(repo, cfg.ScanTimeout)
-		repoClients = append(repoClients, repoClient{
+		rc := &repoClient{
 			client:   client,
+			repo:     repo,
 			interval: repo.ScanInterval,
-		})
+			log:      slog.With(logging.KeyRepository, repo.Name, logging.KeyURL, repo.URL),
+		}
+		if repo.TLS != nil {
+			tlsConfig, err := fetcher.NewTLSConfig(repo.TLS)
+			if err != nil {
+				fatal("Invalid TLS configuration", logging.KeyRepository, repo.Name, logging.ErrorReason(err))
+			}
+			client.SetTLSConfig(tlsConfig)
+		}
+		if repo.Provenance != nil {
+			keyring, err := provenance.LoadKeyring(repo.Provenance.Keyring)
+			if err != nil {
+				fatal("Failed to load keyring", logging.KeyRepository, repo.Name, logging.ErrorReason(err))
+			}
+			rc.verifier = provenance.NewVerifier(keyring, client)
+		}
+		if gen := repo.GenerateIndex; gen != nil {
+			source := gen.Source
+			if source == "" {
+				source = repo.URL
+			}
+			s3 := indexer.S3Options{Endpoint: gen.Endpoint, Region: gen.Region}
+			if c := gen.Credentials; c != nil {
+				s3.Credentials = &indexer.S3Credentials{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken}
+			}
+			src, err := indexer.NewSource(source, s3, &http.Client{Timeout: cfg.ScanTimeout})
+			if err != nil {
+				fatal("Invalid index generation source", logging.KeyRepository, repo.Name, logging.ErrorReason(err))
+			}
+			rc.charts = indexer.NewGenerator(src)
+		}
+		rc.filter, err = analyzer.NewChartFilter(repo.Charts.Include, repo.Charts.Exclude, repo.Charts.Versions)
+		if err != nil {
+			fatal("Invalid chart filter", logging.KeyRepository, repo.Name, logging.ErrorReason(err))
+		}
+		lintCfg := cfg.LintConfigFor(repo)
+		rc.linter, err = analyzer.NewLinter(analyzer.DefaultLintRules(), lintCfg.Severity, lintCfg.Suppress)
+		if err != nil {
+			fatal("Invalid lint configuration", logging.KeyRepository, repo.Name, logging.ErrorReason(err))
+		}
+		repoClients = append(repoClients, rc)
 	}
-	log.Printf("Created %d HTTP client(s)", len(repoClients))
+	slog.Debug("Created HTTP clients", "count", len(repoClients))
 
 	// Initialize metrics
-	metricsCollector := metrics.NewMetrics()
-	log.Println("Prometheus metrics initialized")
+	registry := prometheus.NewRegistry()
+	runtimeCollectors := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}
+	registry.MustRegister(runtimeCollectors...)
+	// In collect-on-scrape mode the exporter's metrics are collected through a collector that
+	// refreshes stale repositories first
+	var exp *exporter
+	var registerer prometheus.Registerer = registry
+	var onScrape *metrics.OnScrapeCollector
+	if cfg.CollectOnScrape {
+		onScrape = metrics.NewOnScrapeCollector(func(ctx context.Context) { exp.refreshStale(ctx) }, cfg.ScanTimeout)
+		registerer = onScrape
+	}
+	metricsCollector := metrics.NewMetrics(registerer)
+	metricsCollector.SetBuildInfo(version, buildCommit())
+	for _, rc := range repoClients {
+		fetched := metricsCollector.FetchedBytes.WithLabelValues(rc.repo.Name)
+		rc.client.OnFetch(func(bytes int64) { fetched.Add(float64(bytes)) })
+	}
+	limits := metrics.SeriesLimits{
+		Global:        cfg.ChartSeries.Limit,
+		PerRepository: make(map[string]int),
+		Aggregate:     cfg.ChartSeries.Overflow == config.OverflowAggregate,
+	}
+	for _, repo := range cfg.Repositories {
+		if repo.MaxChartSeries > 0 {
+			limits.PerRepository[repo.Name] = repo.MaxChartSeries
+		}
+	}
+	metricsCollector.SetSeriesLimits(limits)
+	slog.Debug("Prometheus metrics initialized")
+
+	// Export the same metrics, and scrape traces, to an OpenTelemetry collector
+	var shutdownTelemetry func(context.Context) error
+	if cfg.OTLP != nil {
+		// OTLP exports must not trigger collect-on-scrape fetches, so they read the values
+		// left by the last Prometheus scrape
+		var gatherer prometheus.Gatherer = registry
+		if onScrape != nil {
+			cached := prometheus.NewRegistry()
+			cached.MustRegister(runtimeCollectors...)
+			cached.MustRegister(onScrape.Cached())
+			gatherer = cached
+		}
+		telemetry.SetSecrets(cfg.Secrets())
+		shutdownTelemetry, err = telemetry.Setup(ctx, cfg.OTLP, gatherer, version)
+		if err != nil {
+			fatal("Failed to set up OTLP export", logging.ErrorReason(err))
+		}
+		slog.Info("Exporting metrics and traces over OTLP", "protocol", cfg.OTLP.Protocol, "endpoint", cfg.OTLP.Endpoint)
+	}
 
 	// Initialize HTML generator if enabled
 	var htmlGenerator *web.HTMLGenerator
 	if cfg.EnableHTML {
-		htmlGenerator, err = web.NewHTMLGenerator()
+		htmlGenerator, err = web.NewHTMLGenerator(cfg.HTMLPath)
 		if err != nil {
-			log.Fatalf("Failed to create HTML generator: %v", err)
+			fatal("Failed to create HTML generator", logging.ErrorReason(err))
+		}
+		repositories := make([]web.RepositoryInfo, 0, len(cfg.Repositories))
+		for _, repo := range cfg.Repositories {
+			repositories = append(repositories, web.RepositoryInfo{
+				Name:         repo.Name,
+				URL:          repo.URL,
+				Auth:         repo.Auth.Type(),
+				ScanInterval: repo.ScanInterval,
+			})
+		}
+		htmlGenerator.SetRepositories(repositories)
+		htmlGenerator.SetSecrets(cfg.Secrets())
+
+		// Helm indexes don't record tarball sizes, so chart pages ask the repositories
+		clients := make(map[string]*fetcher.Client, len(repoClients))
+		for _, rc := range repoClients {
+			clients[rc.repo.Name] = rc.client
 		}
-		log.Println("HTML generator initialized")
+		htmlGenerator.SetSizeFunc(func(ctx context.Context, repository, chartURL string) (int64, error) {
+			client, ok := clients[repository]
+			if !ok {
+				return 0, fmt.Errorf("unknown repository %q", repository)
+			}
+			return client.FileSize(ctx, chartURL)
+		})
+		slog.Debug("HTML generator initialized")
+	}
+
+	// Initialize new version notifications if configured
+	var versionNotifier *notifier.Notifier
+	if cfg.Notifications != nil {
+		versionNotifier, err = notifier.New(cfg.Notifications, &http.Client{Timeout: cfg.ScanTimeout})
+		if err != nil {
+			fatal("Failed to create notifier", logging.ErrorReason(err))
+		}
+		slog.Info("Notifications enabled",
+			"subscriptions", len(cfg.Notifications.Subscriptions), "batch_interval", cfg.Notifications.BatchInterval)
 	}
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
-	mux.Handle(cfg.MetricsPath, promhttp.Handler())
+	mux.Handle(cfg.MetricsPath, metricsCollector.InstrumentHandler("metrics",
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})))
 
 	// Add health check endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+	mux.Handle("/health", metricsCollector.InstrumentHandler("health", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte("OK")); err != nil {
-			log.Printf("Error writing health response: %v", err)
+			slog.Warn("Failed to write health response", logging.ErrorReason(err))
 		}
-	})
+	})))
 
 	// Add readiness check endpoint
-	mux.HandleFunc("/ready", func(w http.ResponseWriter, _ *http.Request) {
+	mux.Handle("/ready", metricsCollector.InstrumentHandler("ready", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte("Ready")); err != nil {
-			log.Printf("Error writing readiness response: %v", err)
+			slog.Warn("Failed to write readiness response", logging.ErrorReason(err))
 		}
-	})
+	})))
 
 	if cfg.EnableHTML && htmlGenerator != nil {
-		mux.Handle(cfg.HTMLPath, htmlGenerator)
-		log.Printf("HTML dashboard enabled at %s", cfg.HTMLPath)
+		dashboard := metricsCollector.InstrumentHandler("dashboard", htmlGenerator)
+		mux.Handle(cfg.HTMLPath, dashboard)
+		if !strings.HasSuffix(cfg.HTMLPath, "/") {
+			mux.Handle(cfg.HTMLPath+"/", dashboard) // Chart pages
+		}
+		slog.Info("HTML dashboard enabled", "path", cfg.HTMLPath)
+	}
+
+	// Add dependency graph endpoints
+	graphHandler := web.NewDependencyGraphHandler()
+	instrumentedGraph := metricsCollector.InstrumentHandler("dependencies", graphHandler)
+	mux.Handle("/dependencies.json", instrumentedGraph)
+	mux.Handle("/dependencies.dot", instrumentedGraph)
+
+	// Add feeds of new chart versions
+	feedHandler := web.NewFeedHandler()
+	instrumentedFeeds := metricsCollector.InstrumentHandler("feeds", feedHandler)
+	mux.Handle("/feeds.atom", instrumentedFeeds)
+	mux.Handle("/feeds/", instrumentedFeeds)
+
+	// Add status badges
+	repoNames := make([]string, 0, len(cfg.Repositories))
+	for _, repo := range cfg.Repositories {
+		repoNames = append(repoNames, repo.Name)
+	}
+	badgeHandler := web.NewBadgeHandler(repoNames)
+	mux.Handle("/badge/", metricsCollector.InstrumentHandler("badge", badgeHandler))
+
+	// Add merged index endpoints
+	var indexProxy *web.IndexProxyHandler
+	if len(cfg.IndexGroups) > 0 {
+		groups := make([]web.IndexGroup, 0, len(cfg.IndexGroups))
+		for _, group := range cfg.IndexGroups {
+			groups = append(groups, web.IndexGroup{
+				Name:         group.Name,
+				Repositories: group.Repositories,
+				Filter: analyzer.IndexFilter{
+					ExcludeDeprecated: group.ExcludeDeprecated,
+					LatestVersions:    group.LatestVersions,
+					Charts:            group.Charts,
+				},
+			})
+			slog.Info("Merged index enabled", "group", group.Name, "path", "/index/"+group.Name+"/index.yaml")
+		}
+		indexProxy = web.NewIndexProxyHandler(groups)
+		mux.Handle("/index/", metricsCollector.InstrumentHandler("index", indexProxy))
+	}
+
+	// Add on-demand probe endpoint
+	if cfg.EnableProbe {
+		probeHandler, err := probe.NewHandler(cfg.ProbeModules, cfg.ProbeTargets, cfg.ScanTimeout, cfg.MaxIndexSize)
+		if err != nil {
+			fatal("Failed to create probe handler", logging.ErrorReason(err))
+		}
+		mux.Handle("/probe", metricsCollector.InstrumentHandler("probe", probeHandler))
+		slog.Info("Probe endpoint enabled", "path", "/probe", "modules", len(cfg.ProbeModules))
 	}
 
 	server := &http.Server{
@@ -110,220 +392,497 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
+	exp = &exporter{
+		repoClients:      repoClients,
+		metricsCollector: metricsCollector,
+		htmlGenerator:    htmlGenerator,
+		graphHandler:     graphHandler,
+		feedHandler:      feedHandler,
+		badgeHandler:     badgeHandler,
+		indexProxy:       indexProxy,
+		notifier:         versionNotifier,
+		versions:         analyzer.NewVersionTracker(),
+		analyses:         make(map[string]*analyzer.ChartAnalysis),
+		scrapedAt:        make(map[string]time.Time),
+	}
+	if onScrape != nil {
+		registry.MustRegister(onScrape)
+	}
+
 	// Start HTTP server
 	go func() {
-		log.Printf("Starting HTTP server on :%s", cfg.MetricsPort)
-		log.Printf("Metrics available at http://localhost:%s%s", cfg.MetricsPort, cfg.MetricsPath)
+		slog.Info("Starting HTTP server", "port", cfg.MetricsPort, "metrics_path", cfg.MetricsPath)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start HTTP server: %v", err)
+			fatal("Failed to start HTTP server", logging.ErrorReason(err))
 		}
 	}()
 
-	// Perform initial scrape for all repositories
-	var allClients []*fetcher.Client
-	for _, rc := range repoClients {
-		allClients = append(allClients, rc.client)
+	// Perform initial scrape for all repositories, unless scrapes drive the fetches
+	if !cfg.CollectOnScrape {
+		exp.performScrape(ctx)
+	}
+
+	notifyCtx, stopNotifier := context.WithCancel(ctx)
+	defer stopNotifier()
+	if versionNotifier != nil {
+		go versionNotifier.Run(notifyCtx)
 	}
-	performScrape(ctx, allClients, metricsCollector, htmlGenerator)
 
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	// Create a channel for scrape triggers
-	scrapeChan := make(chan *fetcher.Client, 100)
+	scrapeChan := make(chan *repoClient, 100)
 
 	// Start per-repository scraping goroutines
 	for _, rc := range repoClients {
-		go func(client *fetcher.Client, interval time.Duration) {
-			ticker := time.NewTicker(interval)
-			defer ticker.Stop()
-
-			for {
-				select {
-				case <-ticker.C:
-					scrapeChan <- client
-				case <-sigChan:
-					return
+		if cfg.CollectOnScrape {
+			rc.log.Info("Repository is fetched on scrape", "interval", rc.interval)
+		} else {
+			go func(rc *repoClient) {
+				ticker := time.NewTicker(rc.interval)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ticker.C:
+						scrapeChan <- rc
+					case <-sigChan:
+						return
+					}
 				}
-			}
-		}(rc.client, rc.interval)
-		log.Printf("Started scraper for %s with interval %v", rc.client.RepositoryName(), rc.interval)
+			}(rc)
+			rc.log.Info("Started scraper", "interval", rc.interval)
+		}
+
+		if rc.repo.Watch {
+			go func(rc *repoClient) {
+				err := rc.client.Watch(ctx, func() {
+					rc.log.Info("Index changed on disk")
+					scrapeChan <- rc
+				})
+				if err != nil {
+					rc.log.Error("Stopped watching the index", logging.ErrorReason(err))
+				}
+			}(rc)
+			rc.log.Info("Watching the index for changes")
+		}
 	}
 
 	// Main loop - handle scrapes and signals
-	log.Println("Exporter started successfully")
+	slog.Info("Exporter started successfully")
 	for {
 		select {
-		case client := <-scrapeChan:
+		case rc := <-scrapeChan:
 			// Scrape single repository
-			performSingleRepoScrape(ctx, client, metricsCollector, htmlGenerator)
+			<-exp.scrapeOnce(rc)
 		case sig := <-sigChan:
-			log.Printf("Received signal %v, shutting down...", sig)
+			slog.Info("Shutting down", "signal", sig.String())
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 			if err := server.Shutdown(shutdownCtx); err != nil {
-				log.Printf("Error during shutdown: %v", err)
+				slog.Error("Failed to shut down the HTTP server", logging.ErrorReason(err))
+			}
+			if versionNotifier != nil {
+				stopNotifier()
+				versionNotifier.Flush(shutdownCtx)
+				if err := versionNotifier.Close(); err != nil {
+					slog.Error("Failed to close the dead-letter file", logging.ErrorReason(err))
+				}
+			}
+			if shutdownTelemetry != nil {
+				if err := shutdownTelemetry(shutdownCtx); err != nil {
+					slog.Error("Failed to flush OTLP exports", logging.ErrorReason(err))
+				}
 			}
-			log.Println("Exporter stopped")
+			slog.Info("Exporter stopped")
 			return
 		}
 	}
 }
 
 // performScrape scrapes all repositories (used for initial scrape)
-func performScrape(ctx context.Context, clients []*fetcher.Client, metricsCollector *metrics.Metrics, htmlGenerator *web.HTMLGenerator) {
-	log.Println("Starting initial scrape...")
+func (e *exporter) performScrape(ctx context.Context) {
+	slog.Info("Starting initial scrape")
 	overallStartTime := time.Now()
 
-	// Aggregate analysis from all repositories for HTML dashboard
-	var totalAnalysis *analyzer.ChartAnalysis
-
-	for _, client := range clients {
-		repoName := client.RepositoryName()
-		log.Printf("  Fetching from repository: %s", repoName)
-		startTime := time.Now()
-
-		// Fetch index.yaml
-		data, err := client.GetIndexYAML(ctx)
-		if err != nil {
-			log.Printf("  ERROR: Failed to fetch index.yaml from %s: %v", repoName, err)
-			metricsCollector.RecordError(repoName)
-			continue
-		}
-
-		// Parse index
-		index, err := analyzer.ParseIndex(data)
-		if err != nil {
-			log.Printf("  ERROR: Failed to parse index.yaml from %s: %v", repoName, err)
-			metricsCollector.RecordError(repoName)
-			continue
-		}
-
-		// Analyze charts with repository name and URL
-		analysis := analyzer.AnalyzeChartsWithRepo(index, repoName, client.RepositoryURL())
-		duration := time.Since(startTime)
-		log.Printf("  Repository %s: %d charts, %d versions (in %v)", repoName, analysis.TotalCharts, analysis.TotalVersions, duration)
-
-		// Update per-repository metrics
-		metricsCollector.Update(repoName, analysis)
-		metricsCollector.RecordSuccess(repoName)
-		metricsCollector.ScrapeDuration.WithLabelValues(repoName).Observe(duration.Seconds())
-
-		// Merge with total analysis for HTML dashboard
-		if totalAnalysis == nil {
-			totalAnalysis = analysis
-		} else {
-			totalAnalysis = mergeAnalysis(totalAnalysis, analysis)
+	var scraped []*analyzer.ChartAnalysis
+	for _, rc := range e.repoClients {
+		repoCtx, span := telemetry.StartRepositorySpan(ctx, "scrape", rc.repo.Name, rc.repo.URL)
+		analysis, err := e.scrapeRepository(repoCtx, rc)
+		telemetry.EndSpan(span, err)
+		if err == nil {
+			scraped = append(scraped, analysis)
 		}
 	}
 
-	if totalAnalysis == nil {
-		log.Println("ERROR: No successful scrapes")
+	if len(scraped) == 0 {
+		slog.Error("No successful scrapes")
 		return
 	}
 
-	// Update HTML dashboard with aggregated data if enabled
-	if htmlGenerator != nil {
-		htmlGenerator.Update(totalAnalysis)
+	// Compare mirrors with their upstreams now that every repository has been scraped
+	for _, rc := range e.repoClients {
+		e.updateMirrorDrift(rc.repo.Name)
 	}
+	e.updateDependencyGraph()
+	e.updateCombinedAges()
 
+	totalAnalysis := analyzer.MergeAnalyses(scraped...)
 	overallDuration := time.Since(overallStartTime)
-	log.Printf("Initial scrape completed in %v", overallDuration)
-	log.Printf("  Total charts: %d", totalAnalysis.TotalCharts)
-	log.Printf("  Total versions: %d", totalAnalysis.TotalVersions)
+	attrs := []any{logging.KeyDuration, overallDuration, logging.KeyCharts, totalAnalysis.TotalCharts, "versions", totalAnalysis.TotalVersions}
 	if !totalAnalysis.OldestChartDate.IsZero() {
-		log.Printf("  Oldest chart: %s", totalAnalysis.OldestChartDate.Format("2006-01-02 15:04:05"))
+		attrs = append(attrs, "oldest_chart", totalAnalysis.OldestChartDate)
 	}
 	if !totalAnalysis.NewestChartDate.IsZero() {
-		log.Printf("  Newest chart: %s", totalAnalysis.NewestChartDate.Format("2006-01-02 15:04:05"))
+		attrs = append(attrs, "newest_chart", totalAnalysis.NewestChartDate)
 	}
+	slog.Info("Initial scrape completed", attrs...)
 }
 
-// performSingleRepoScrape scrapes a single repository and updates its metrics
-func performSingleRepoScrape(ctx context.Context, client *fetcher.Client, metricsCollector *metrics.Metrics, htmlGenerator *web.HTMLGenerator) {
-	repoName := client.RepositoryName()
-	log.Printf("Scraping repository: %s", repoName)
-	startTime := time.Now()
+// performSingleRepoScrape scrapes a single repository and refreshes the cross-repository
+// metrics it takes part in
+func (e *exporter) performSingleRepoScrape(ctx context.Context, rc *repoClient) {
+	var err error
+	ctx, span := telemetry.StartRepositorySpan(ctx, "scrape", rc.repo.Name, rc.repo.URL)
+	defer func() { telemetry.EndSpan(span, err) }()
 
-	// Fetch index.yaml
-	data, err := client.GetIndexYAML(ctx)
-	if err != nil {
-		log.Printf("ERROR: Failed to fetch index.yaml from %s: %v", repoName, err)
-		metricsCollector.RecordError(repoName)
+	if _, err = e.scrapeRepository(ctx, rc); err != nil {
 		return
 	}
 
-	// Parse index
-	index, err := analyzer.ParseIndex(data)
-	if err != nil {
-		log.Printf("ERROR: Failed to parse index.yaml from %s: %v", repoName, err)
-		metricsCollector.RecordError(repoName)
-		return
+	// Refresh mirror drift for any mirror relationship this repository takes part in
+	for _, other := range e.repoClients {
+		if other.repo.Name == rc.repo.Name || other.repo.MirrorOf == rc.repo.Name {
+			e.updateMirrorDrift(other.repo.Name)
+		}
 	}
+	e.updateDependencyGraph()
+	e.updateCombinedAges()
+}
 
-	// Analyze charts with repository name and URL
-	analysis := analyzer.AnalyzeChartsWithRepo(index, repoName, client.RepositoryURL())
+// scrapeRepository analyzes a repository and publishes the result to its metrics,
+// notifications and the dashboard. ctx carries the scrape span: the fetch and analyze
+// spans of analyzeRepository and the dashboard span are its children.
+func (e *exporter) scrapeRepository(ctx context.Context, rc *repoClient) (*analyzer.ChartAnalysis, error) {
+	repoName := rc.client.RepositoryName()
+	rc.log.Debug("Scraping repository")
+	startTime := time.Now()
+
+	analysis, err := e.analyzeRepository(ctx, rc)
+	if err != nil {
+		rc.log.Error("Failed to scrape repository", logging.ErrorReason(err))
+		e.recordFailure(repoName, err)
+		return nil, err
+	}
 	duration := time.Since(startTime)
-	log.Printf("Repository %s scraped in %v: %d charts, %d versions", repoName, duration, analysis.TotalCharts, analysis.TotalVersions)
+	rc.log.Info("Repository scraped",
+		logging.KeyCharts, analysis.TotalCharts, "versions", analysis.TotalVersions, logging.KeyDuration, duration)
 
 	// Update per-repository metrics
-	metricsCollector.Update(repoName, analysis)
-	metricsCollector.RecordSuccess(repoName)
-	metricsCollector.ScrapeDuration.WithLabelValues(repoName).Observe(duration.Seconds())
+	e.metricsCollector.Update(repoName, analysis)
+	e.metricsCollector.UpdateLint(repoName, rc.linter.Rules(), analysis.LintViolations)
+	e.metricsCollector.RecordSuccess(repoName)
+	e.metricsCollector.ScrapeDuration.WithLabelValues(repoName).Observe(duration.Seconds())
+	e.notifyNewVersions(repoName, analysis)
+	e.storeAnalysis(repoName, analysis)
 
 	// Update HTML dashboard with this repo's data if enabled
-	// The HTML generator will merge this with other repositories' data
-	if htmlGenerator != nil {
-		htmlGenerator.Update(analysis)
+	// The HTML generator merges it with the other repositories' data
+	if e.htmlGenerator != nil {
+		_, dashboardSpan := telemetry.StartRepositorySpan(ctx, "dashboard", repoName, rc.repo.URL)
+		e.htmlGenerator.UpdateRepository(repoName, analysis)
+		telemetry.EndSpan(dashboardSpan, nil)
 	}
+	return analysis, nil
+}
+
+// scrapeOnce scrapes a repository, sharing the result with concurrent callers scraping the same repository
+func (e *exporter) scrapeOnce(rc *repoClient) <-chan singleflight.Result {
+	return e.scrapes.DoChan(rc.repo.Name, func() (interface{}, error) {
+		e.performSingleRepoScrape(context.Background(), rc)
+		return nil, nil
+	})
 }
 
-// mergeAnalysis combines two chart analyses
-func mergeAnalysis(a1, a2 *analyzer.ChartAnalysis) *analyzer.ChartAnalysis {
-	merged := &analyzer.ChartAnalysis{
-		TotalCharts:   a1.TotalCharts + a2.TotalCharts,
-		TotalVersions: a1.TotalVersions + a2.TotalVersions,
-		ChartsInfo:    append(a1.ChartsInfo, a2.ChartsInfo...),
+// refreshStale concurrently scrapes every repository whose latest scrape is older than its
+// scan interval, returning once they are done or ctx is; unfinished scrapes carry on in the background
+func (e *exporter) refreshStale(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, rc := range e.repoClients {
+		e.mu.RLock()
+		scrapedAt, ok := e.scrapedAt[rc.repo.Name]
+		e.mu.RUnlock()
+		if ok && time.Since(scrapedAt) < rc.interval {
+			continue
+		}
+
+		wg.Add(1)
+		go func(rc *repoClient) {
+			defer wg.Done()
+			select {
+			case <-e.scrapeOnce(rc):
+			case <-ctx.Done():
+				rc.log.Warn("Scrape did not finish within the scan timeout, serving previous data")
+			}
+		}(rc)
 	}
+	wg.Wait()
+}
 
-	// Merge date statistics
+// analyzeRepository analyzes a repository's published index, or an index generated from
+// its chart tarballs when configured, then verifies provenance if configured
+func (e *exporter) analyzeRepository(ctx context.Context, rc *repoClient) (*analyzer.ChartAnalysis, error) {
+	repoName := rc.client.RepositoryName()
+	e.metricsCollector.ScrapesInFlight.Inc()
+	defer e.metricsCollector.ScrapesInFlight.Dec()
+
+	e.mu.Lock()
+	e.scrapedAt[repoName] = time.Now()
+	e.mu.Unlock()
+
+	var analysis *analyzer.ChartAnalysis
+	var err error
 	switch {
-	case !a1.OldestChartDate.IsZero() && !a2.OldestChartDate.IsZero():
-		if a1.OldestChartDate.Before(a2.OldestChartDate) {
-			merged.OldestChartDate = a1.OldestChartDate
-		} else {
-			merged.OldestChartDate = a2.OldestChartDate
+	case rc.charts != nil && rc.repo.GenerateIndex.When == config.GenerateAlways:
+		analysis, err = e.analyzeGeneratedIndex(ctx, rc)
+	case rc.charts != nil:
+		analysis, err = e.analyzePublishedIndex(ctx, rc)
+		if err != nil {
+			rc.log.Warn("Published index is unusable, generating it from chart tarballs", logging.ErrorReason(err))
+			analysis, err = e.analyzeGeneratedIndex(ctx, rc)
 		}
-	case !a1.OldestChartDate.IsZero():
-		merged.OldestChartDate = a1.OldestChartDate
 	default:
-		merged.OldestChartDate = a2.OldestChartDate
+		analysis, err = e.analyzePublishedIndex(ctx, rc)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	switch {
-	case !a1.NewestChartDate.IsZero() && !a2.NewestChartDate.IsZero():
-		if a1.NewestChartDate.After(a2.NewestChartDate) {
-			merged.NewestChartDate = a1.NewestChartDate
-		} else {
-			merged.NewestChartDate = a2.NewestChartDate
+	verifyProvenance(ctx, rc, analysis)
+	return analysis, nil
+}
+
+// analyzePublishedIndex fetches a repository's index and analyzes it as it is decoded
+func (e *exporter) analyzePublishedIndex(ctx context.Context, rc *repoClient) (*analyzer.ChartAnalysis, error) {
+	repoName := rc.client.RepositoryName()
+
+	_, fetchSpan := telemetry.StartRepositorySpan(ctx, "fetch", repoName, rc.repo.URL)
+	body, err := rc.client.OpenIndex(ctx)
+	telemetry.EndSpan(fetchSpan, err)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	// Charts are analyzed while the index is parsed, and the body is downloaded while it is
+	// parsed, so the parse span within the analyze span includes the transfer
+	analyzeCtx, analyzeSpan := telemetry.StartRepositorySpan(ctx, "analyze", repoName, rc.repo.URL)
+	defer func() { telemetry.EndSpan(analyzeSpan, err) }()
+	_, parseSpan := telemetry.StartRepositorySpan(analyzeCtx, "parse", repoName, rc.repo.URL)
+	acc := e.newIndexAnalysis(rc)
+	index, err := analyzer.WalkIndex(body, acc.addChart)
+	e.metricsCollector.RecordTransfer(repoName, body.TransferredBytes(), body.DecodedBytes())
+	parseSpan.SetAttributes(
+		attribute.Int64("helm.index.transferred_bytes", body.TransferredBytes()),
+		attribute.Int64("helm.index.decoded_bytes", body.DecodedBytes()),
+	)
+	telemetry.EndSpan(parseSpan, err)
+	if err != nil {
+		return nil, err
+	}
+
+	e.metricsCollector.UpdateIndex(repoName, body.DecodedBytes(), index, body.LastModified())
+	e.metricsCollector.SetIndexRegenerated(repoName, false)
+
+	analysis := acc.finish(index)
+	analysis.IndexSize = body.DecodedBytes()
+	return analysis, nil
+}
+
+// analyzeGeneratedIndex builds an index from the repository's chart tarballs and analyzes it
+func (e *exporter) analyzeGeneratedIndex(ctx context.Context, rc *repoClient) (*analyzer.ChartAnalysis, error) {
+	repoName := rc.client.RepositoryName()
+
+	_, fetchSpan := telemetry.StartRepositorySpan(ctx, "fetch", repoName, rc.repo.URL)
+	fetchSpan.SetAttributes(attribute.Bool("helm.index.generated", true))
+	index, err := rc.charts.Generate(ctx)
+	telemetry.EndSpan(fetchSpan, err)
+	if index == nil {
+		return nil, fmt.Errorf("failed to generate index: %w", err)
+	}
+	if err != nil {
+		rc.log.Warn("Skipped unreadable charts while generating the index", logging.ErrorReason(err))
+	}
+	e.metricsCollector.SetIndexRegenerated(repoName, true)
+
+	_, analyzeSpan := telemetry.StartRepositorySpan(ctx, "analyze", repoName, rc.repo.URL)
+	acc := e.newIndexAnalysis(rc)
+	for _, chartName := range index.ChartNames() {
+		if err := acc.addChart(chartName, index.Entries[chartName]); err != nil {
+			telemetry.EndSpan(analyzeSpan, err)
+			return nil, err
 		}
-	case !a1.NewestChartDate.IsZero():
-		merged.NewestChartDate = a1.NewestChartDate
-	default:
-		merged.NewestChartDate = a2.NewestChartDate
 	}
+	analysis := acc.finish(index)
+	telemetry.EndSpan(analyzeSpan, nil)
+	return analysis, nil
+}
 
-	// For median, we'll just use the median of the two medians (approximation)
-	switch {
-	case !a1.MedianChartDate.IsZero() && !a2.MedianChartDate.IsZero():
-		avg := (a1.MedianChartDate.Unix() + a2.MedianChartDate.Unix()) / 2
-		merged.MedianChartDate = time.Unix(avg, 0)
-	case !a1.MedianChartDate.IsZero():
-		merged.MedianChartDate = a1.MedianChartDate
-	default:
-		merged.MedianChartDate = a2.MedianChartDate
+// indexAnalysis accumulates everything derived from a repository's index, one chart at a time
+type indexAnalysis struct {
+	exporter   *exporter
+	rc         *repoClient
+	builder    *analyzer.AnalysisBuilder
+	violations []analyzer.LintViolation
+	entries    map[string][]analyzer.ChartVersionInfo // nil unless a merged index needs them
+}
+
+func (e *exporter) newIndexAnalysis(rc *repoClient) *indexAnalysis {
+	acc := &indexAnalysis{
+		exporter: e,
+		rc:       rc,
+		builder:  analyzer.NewAnalysisBuilder(rc.client.RepositoryName(), rc.client.RepositoryURL()),
+	}
+	acc.builder.SetFilter(rc.filter)
+	if e.indexProxy != nil && e.indexProxy.Includes(rc.repo.Name) {
+		acc.entries = make(map[string][]analyzer.ChartVersionInfo)
+	}
+	return acc
+}
+
+// addChart analyzes and lints the versions of a chart that pass the repository's filter
+func (a *indexAnalysis) addChart(chartName string, versions []analyzer.ChartVersionInfo) error {
+	versions, ok := a.builder.AddChart(chartName, versions)
+	if !ok {
+		return nil
+	}
+	a.violations = append(a.violations, a.rc.linter.LintEntry(chartName, versions, a.rc.repo.Name, a.rc.repo.URL)...)
+	if a.entries != nil {
+		a.entries[chartName] = versions
+	}
+	return nil
+}
+
+// finish lints the index metadata, publishes the entries to the merged index and returns the analysis
+func (a *indexAnalysis) finish(index *analyzer.HelmIndex) *analyzer.ChartAnalysis {
+	if a.entries != nil {
+		a.exporter.indexProxy.Update(a.rc.repo.Name, a.rc.repo.URL, a.entries)
+	}
+
+	analysis := a.builder.Analysis()
+	analysis.LintViolations = append(a.rc.linter.LintIndex(index, a.rc.repo.Name, a.rc.repo.URL), a.violations...)
+	return analysis
+}
+
+// recordFailure reports a failed scrape of a repository in metrics, badges and the repository overview
+func (e *exporter) recordFailure(repoName string, err error) {
+	e.metricsCollector.RecordError(repoName)
+	e.badgeHandler.RecordFailure(repoName)
+	if e.htmlGenerator != nil {
+		e.htmlGenerator.RecordScrape(repoName, nil, err)
+	}
+}
+
+// notifyNewVersions queues notifications for the versions never seen before in the repository
+func (e *exporter) notifyNewVersions(repoName string, analysis *analyzer.ChartAnalysis) {
+	if e.notifier == nil {
+		return
+	}
+	if added := e.versions.Observe(repoName, analysis); len(added) > 0 {
+		slog.Info("New chart versions published", logging.KeyRepository, repoName, "versions", len(added))
+		e.notifier.Notify(added)
+	}
+}
+
+// storeAnalysis remembers the latest analysis of a repository for cross-repository checks,
+// feeds, badges and the repository overview
+func (e *exporter) storeAnalysis(repoName string, analysis *analyzer.ChartAnalysis) {
+	e.feedHandler.Update(repoName, analysis)
+	e.badgeHandler.Update(repoName, analysis)
+	if e.htmlGenerator != nil {
+		e.htmlGenerator.RecordScrape(repoName, analysis, nil)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.analyses[repoName] = analysis
+}
+
+// updateMirrorDrift compares a mirror repository with its upstream and exports the drift
+// It is a no-op for repositories that are not mirrors or when either side has not been scraped yet
+func (e *exporter) updateMirrorDrift(repoName string) {
+	var (
+		upstream string
+		filter   *analyzer.ChartFilter
+	)
+	for _, rc := range e.repoClients {
+		if rc.repo.Name == repoName {
+			upstream, filter = rc.repo.MirrorOf, rc.filter
+			break
+		}
+	}
+	if upstream == "" {
+		return
+	}
+
+	e.mu.RLock()
+	mirrorAnalysis := e.analyses[repoName]
+	upstreamAnalysis := e.analyses[upstream]
+	e.mu.RUnlock()
+	if mirrorAnalysis == nil || upstreamAnalysis == nil {
+		return
+	}
+
+	drift := analyzer.CompareMirror(mirrorAnalysis, upstreamAnalysis, filter)
+	e.metricsCollector.UpdateMirror(repoName, upstream, drift)
+
+	missing := 0
+	for _, d := range drift {
+		missing += len(d.MissingVersions)
+	}
+	if missing > 0 {
+		slog.Info("Mirror is missing versions", logging.KeyRepository, repoName, "upstream", upstream, "missing_versions", missing)
+	}
+}
+
+// verifyProvenance checks chart signatures if the repository has a keyring configured
+func verifyProvenance(ctx context.Context, rc *repoClient, analysis *analyzer.ChartAnalysis) {
+	if rc.verifier == nil {
+		return
+	}
+	if err := rc.verifier.Verify(ctx, analysis); err != nil {
+		rc.log.Warn("Provenance verification incomplete", logging.ErrorReason(err))
+	}
+}
+
+// updateDependencyGraph rebuilds the cross-repository dependency graph from the latest analyses
+func (e *exporter) updateDependencyGraph() {
+	e.mu.RLock()
+	repos := make([]analyzer.RepositoryAnalysis, 0, len(e.repoClients))
+	for _, rc := range e.repoClients {
+		repos = append(repos, analyzer.RepositoryAnalysis{
+			Name:     rc.repo.Name,
+			URL:      rc.repo.URL,
+			Analysis: e.analyses[rc.repo.Name],
+		})
+	}
+	e.mu.RUnlock()
+
+	graph := analyzer.BuildDependencyGraph(repos)
+	e.metricsCollector.UpdateDependencies(graph)
+	e.graphHandler.Update(graph)
+}
+
+// updateCombinedAges exports the release date quantiles of all repositories combined
+func (e *exporter) updateCombinedAges() {
+	e.mu.RLock()
+	analyses := make([]*analyzer.ChartAnalysis, 0, len(e.repoClients))
+	for _, rc := range e.repoClients {
+		analyses = append(analyses, e.analyses[rc.repo.Name])
 	}
+	e.mu.RUnlock()
 
-	return merged
+	e.metricsCollector.UpdateCombinedAges(analyzer.MergeReleaseDates(analyses...))
 }
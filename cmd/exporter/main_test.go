@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/fetcher"
+	"github.com/obezpalko/helm-repo-exporter/internal/metrics"
+	"github.com/obezpalko/helm-repo-exporter/internal/web"
+	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  app:
+  - name: app
+    version: 1.0.0
+    created: "2024-01-01T00:00:00Z"
+    urls:
+    - app-1.0.0.tgz
+generated: "2024-01-01T00:00:00Z"
+`
+
+// newTestExporter creates an exporter scraping a single repository served by an httptest server
+func newTestExporter(t *testing.T) *exporter {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testIndex))
+	}))
+	t.Cleanup(server.Close)
+
+	repo := config.Repository{Name: "stable", URL: server.URL + "/index.yaml"}
+	linter, err := analyzer.NewLinter(analyzer.DefaultLintRules(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	htmlGenerator, err := web.NewHTMLGenerator("/charts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &exporter{
+		repoClients: []*repoClient{{
+			client: fetcher.NewClient(repo, 0),
+			repo:   repo,
+			linter: linter,
+			log:    slog.Default(),
+		}},
+		metricsCollector: metrics.NewMetrics(prometheus.NewRegistry()),
+		htmlGenerator:    htmlGenerator,
+		graphHandler:     web.NewDependencyGraphHandler(),
+		feedHandler:      web.NewFeedHandler(),
+		badgeHandler:     web.NewBadgeHandler([]string{repo.Name}),
+		analyses:         make(map[string]*analyzer.ChartAnalysis),
+		scrapedAt:        make(map[string]time.Time),
+	}
+}
+
+func TestScrapeSpans(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	e := newTestExporter(t)
+	for name, scrape := range map[string]func(){
+		"initial":  func() { e.performScrape(context.Background()) },
+		"periodic": func() { e.performSingleRepoScrape(context.Background(), e.repoClients[0]) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			spans.Reset()
+			scrape()
+
+			byName := make(map[string]tracetest.SpanStub)
+			for _, span := range spans.GetSpans() {
+				byName[span.Name] = span
+			}
+			scrapeSpan, ok := byName["scrape"]
+			if !ok || len(byName) != 5 {
+				t.Fatalf("Expected scrape, fetch, analyze, parse and dashboard spans, got %v", byName)
+			}
+			parents := map[string]string{"fetch": "scrape", "analyze": "scrape", "dashboard": "scrape", "parse": "analyze"}
+			for child, parent := range parents {
+				span := byName[child]
+				if span.Parent.SpanID() != byName[parent].SpanContext.SpanID() {
+					t.Errorf("Expected %s to be a child of %s", child, parent)
+				}
+				if span.EndTime.After(byName[parent].EndTime) {
+					t.Errorf("Expected %s to end before its parent %s", child, parent)
+				}
+				if span.StartTime.Before(scrapeSpan.StartTime) {
+					t.Errorf("Expected %s to start within the scrape span", child)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/indexer"
+)
+
+// runReindex implements `exporter reindex`, which writes an index generated from chart
+// tarballs so it can be compared with the published one
+func runReindex(args []string) error {
+	flags := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	source := flags.String("source", "", "Chart tarballs to index: file:///path or s3://bucket/prefix (required)")
+	endpoint := flags.String("endpoint", "", "S3 endpoint for S3-compatible storage")
+	region := flags.String("region", "", "S3 region; defaults to AWS_REGION, AWS_DEFAULT_REGION or us-east-1")
+	baseURL := flags.String("url", "", "Base URL prepended to chart URLs; relative URLs are written if empty")
+	output := flags.String("output", "-", "File to write the index to, - for stdout")
+	timeout := flags.Duration("timeout", 5*time.Minute, "Timeout for generating the index")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *source == "" {
+		flags.Usage()
+		return fmt.Errorf("-source is required")
+	}
+
+	src, err := indexer.NewSource(*source, indexer.S3Options{Endpoint: *endpoint, Region: *region}, &http.Client{Timeout: 30 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	index, err := indexer.Generate(ctx, src)
+	if index == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: skipped unreadable charts: %v\n", err)
+	}
+
+	if *baseURL != "" {
+		base := strings.TrimSuffix(*baseURL, "/")
+		for _, versions := range index.Entries {
+			for i := range versions {
+				for j, u := range versions[i].URLs {
+					versions[i].URLs[j] = base + "/" + u
+				}
+			}
+		}
+	}
+
+	if *output == "-" {
+		return analyzer.WriteIndex(os.Stdout, index)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", *output, err)
+	}
+	if err := analyzer.WriteIndex(f, index); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,45 +1,98 @@
 package analyzer
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"gopkg.in/yaml.v3"
 )
 
 // HelmIndex represents the structure of a Helm repository index.yaml
 type HelmIndex struct {
-	APIVersion string                        `yaml:"apiVersion"`
-	Entries    map[string][]ChartVersionInfo `yaml:"entries"`
-	Generated  time.Time                     `yaml:"generated"`
+	APIVersion string                        `yaml:"apiVersion" json:"apiVersion"`
+	Entries    map[string][]ChartVersionInfo `yaml:"entries" json:"entries"`
+	Generated  time.Time                     `yaml:"generated" json:"generated"`
 }
 
-// ChartVersionInfo represents a single chart version in the index
+// ChartNames returns the chart names of the index in sorted order
+func (i *HelmIndex) ChartNames() []string {
+	names := make([]string, 0, len(i.Entries))
+	for name := range i.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChartVersionInfo represents a single chart version in the index.
+// Its metadata fields mirror Chart.yaml, so a Chart.yaml decodes into it directly.
 type ChartVersionInfo struct {
-	Name        string    `yaml:"name"`
-	Version     string    `yaml:"version"`
-	Description string    `yaml:"description"`
-	Icon        string    `yaml:"icon"`
-	Created     time.Time `yaml:"created"`
-	URLs        []string  `yaml:"urls"`
+	APIVersion   string            `yaml:"apiVersion,omitempty" json:"apiVersion,omitempty"`
+	Name         string            `yaml:"name" json:"name"`
+	Version      string            `yaml:"version" json:"version"`
+	AppVersion   string            `yaml:"appVersion,omitempty" json:"appVersion,omitempty"`
+	Type         string            `yaml:"type,omitempty" json:"type,omitempty"`
+	Description  string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Home         string            `yaml:"home,omitempty" json:"home,omitempty"`
+	KubeVersion  string            `yaml:"kubeVersion,omitempty" json:"kubeVersion,omitempty"`
+	Icon         string            `yaml:"icon,omitempty" json:"icon,omitempty"`
+	Keywords     []string          `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+	Sources      []string          `yaml:"sources,omitempty" json:"sources,omitempty"`
+	Maintainers  []Maintainer      `yaml:"maintainers,omitempty" json:"maintainers,omitempty"`
+	Created      time.Time         `yaml:"created,omitempty" json:"created"`
+	URLs         []string          `yaml:"urls" json:"urls"`
+	Digest       string            `yaml:"digest,omitempty" json:"digest,omitempty"`
+	Deprecated   bool              `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	Dependencies []Dependency      `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+	Annotations  map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+// Maintainer is a chart maintainer as declared in Chart.yaml
+type Maintainer struct {
+	Name  string `yaml:"name" json:"name"`
+	Email string `yaml:"email,omitempty" json:"email,omitempty"`
+	URL   string `yaml:"url,omitempty" json:"url,omitempty"`
+}
+
+// Dependency is a chart dependency as declared in Chart.yaml and copied into the index
+type Dependency struct {
+	Name       string   `yaml:"name" json:"name"`
+	Version    string   `yaml:"version" json:"version"`
+	Repository string   `yaml:"repository" json:"repository"`
+	Condition  string   `yaml:"condition,omitempty" json:"condition,omitempty"`
+	Tags       []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Alias      string   `yaml:"alias,omitempty" json:"alias,omitempty"`
 }
 
 // ChartAnalysis contains analyzed information about charts
 type ChartAnalysis struct {
-	TotalCharts     int
-	TotalVersions   int
-	ChartsInfo      []ChartInfo
-	OldestChartDate time.Time
-	NewestChartDate time.Time
-	MedianChartDate time.Time
+	TotalCharts      int
+	TotalVersions    int
+	DeprecatedCharts int
+	FilteredCharts   int // Charts dropped by the repository's chart filter
+	ChartsInfo       []ChartInfo
+	OldestChartDate  time.Time
+	NewestChartDate  time.Time
+	MedianChartDate  time.Time
+	LintViolations   []LintViolation
+	IndexSize        int64 // Decoded size of the published index in bytes, 0 for generated indexes
+
+	// ReleaseDates are the creation dates of every version, sorted, so that the date
+	// statistics of merged analyses are exact
+	ReleaseDates []time.Time
 }
 
 // ChartInfo contains information about a single chart
 type ChartInfo struct {
 	Name           string
 	Repository     string
+	RepositoryURL  string // Repository base URL, as passed to helm repo add
 	VersionCount   int
 	Versions       []string
 	VersionDetails []VersionDetail
@@ -48,24 +101,100 @@ type ChartInfo struct {
 	MedianVersion  time.Time
 	Icon           string
 	Description    string
+	Deprecated     bool         // Taken from the latest (first) index entry, as Helm does
+	Dependencies   []Dependency // Dependencies of the latest version
+
+	// Metadata of the latest version
+	AppVersion  string
+	Type        string
+	Home        string
+	Sources     []string
+	Keywords    []string
+	Maintainers []Maintainer
+
+	// Provenance verification results, populated only when a keyring is configured
+	SignedVersions   int
+	UnsignedVersions int
+	InvalidVersions  int
 }
 
 // VersionDetail contains detailed information about a chart version
 type VersionDetail struct {
-	Version string
-	Created time.Time
-	URL     string
+	Version    string
+	AppVersion string
+	Created    time.Time
+	URL        string
+	Digest     string
+	Signature  SignatureStatus
+}
+
+// SignatureStatus is the result of verifying a chart version's provenance file
+type SignatureStatus string
+
+const (
+	// SignatureUnknown means the version was not verified (no keyring or fetch failure)
+	SignatureUnknown SignatureStatus = ""
+	// SignatureSigned means the .prov file was signed by a trusted key and matches the tarball
+	SignatureSigned SignatureStatus = "signed"
+	// SignatureUnsigned means no .prov file was published for the version
+	SignatureUnsigned SignatureStatus = "unsigned"
+	// SignatureInvalid means the .prov file has a bad signature or does not match the tarball
+	SignatureInvalid SignatureStatus = "invalid"
+)
+
+// ProvenanceChecked reports whether any version of the chart had its signature verified
+func (c ChartInfo) ProvenanceChecked() bool {
+	return c.SignedVersions+c.UnsignedVersions+c.InvalidVersions > 0
+}
+
+// SignatureSummary returns an overall signature status for the chart:
+// invalid if any version is invalid, signed if every verified version is signed,
+// unsigned otherwise
+func (c ChartInfo) SignatureSummary() SignatureStatus {
+	switch {
+	case !c.ProvenanceChecked():
+		return SignatureUnknown
+	case c.InvalidVersions > 0:
+		return SignatureInvalid
+	case c.UnsignedVersions == 0:
+		return SignatureSigned
+	default:
+		return SignatureUnsigned
+	}
 }
 
-// ParseIndex parses the Helm index.yaml content
+// ParseIndex parses the Helm index.yaml content.
+// JSON indexes (index.json, or a JSON-encoded index.yaml) are decoded with encoding/json,
+// which is much faster than the YAML decoder; anything it rejects falls back to YAML.
 func ParseIndex(data []byte) (*HelmIndex, error) {
 	var index HelmIndex
+	if isJSON(data) && json.Unmarshal(data, &index) == nil {
+		return &index, nil
+	}
+
+	index = HelmIndex{}
 	if err := yaml.Unmarshal(data, &index); err != nil {
 		return nil, fmt.Errorf("failed to parse index.yaml: %w", err)
 	}
 	return &index, nil
 }
 
+// WriteIndex writes the index as YAML in the layout Helm uses
+func WriteIndex(w io.Writer, index *HelmIndex) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(index); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return enc.Close()
+}
+
+// isJSON reports whether data looks like a JSON object
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
 // AnalyzeCharts performs analysis on the Helm index
 func AnalyzeCharts(index *HelmIndex) *ChartAnalysis {
 	return AnalyzeChartsWithRepo(index, "", "")
@@ -80,9 +209,7 @@ func resolveChartURL(chartURL, repoURL string) string {
 	}
 
 	// URL is relative, construct the full URL from the repository base
-	// Remove trailing /index.yaml from repoURL if present
-	baseURL := strings.TrimSuffix(repoURL, "/index.yaml")
-	baseURL = strings.TrimSuffix(baseURL, "/")
+	baseURL := repositoryBaseURL(repoURL)
 
 	// Ensure chartURL doesn't start with /
 	chartURL = strings.TrimPrefix(chartURL, "/")
@@ -90,80 +217,164 @@ func resolveChartURL(chartURL, repoURL string) string {
 	return baseURL + "/" + chartURL
 }
 
+// indexFileNames are the index file names stripped from repository URLs to find their base
+var indexFileNames = []string{"/index.yaml", "/index.yaml.gz", "/index.json"}
+
+// repositoryBaseURL returns the repository URL without its index file name and trailing slash
+func repositoryBaseURL(repoURL string) string {
+	for _, name := range indexFileNames {
+		if base, ok := strings.CutSuffix(repoURL, name); ok {
+			return base
+		}
+	}
+	return strings.TrimSuffix(repoURL, "/")
+}
+
 // AnalyzeChartsWithRepo performs analysis on the Helm index with repository name and URL
 func AnalyzeChartsWithRepo(index *HelmIndex, repository string, repoURL string) *ChartAnalysis {
-	analysis := &ChartAnalysis{
-		TotalCharts: len(index.Entries),
-		ChartsInfo:  make([]ChartInfo, 0, len(index.Entries)),
+	builder := NewAnalysisBuilder(repository, repoURL)
+	for _, chartName := range index.ChartNames() {
+		builder.AddChart(chartName, index.Entries[chartName])
 	}
+	return builder.Analysis()
+}
 
-	var allDates []time.Time
+// AnalysisBuilder accumulates a ChartAnalysis one chart at a time, so an index can be
+// analyzed while it is being decoded without keeping every ChartVersionInfo in memory
+type AnalysisBuilder struct {
+	repository string
+	repoURL    string
+	filter     *ChartFilter
+	analysis   *ChartAnalysis
+	allDates   []time.Time
+}
 
-	for chartName, versions := range index.Entries {
-		if len(versions) == 0 {
-			continue
-		}
+// NewAnalysisBuilder creates an empty builder for the given repository
+func NewAnalysisBuilder(repository, repoURL string) *AnalysisBuilder {
+	return &AnalysisBuilder{
+		repository: repository,
+		repoURL:    repoURL,
+		analysis:   &ChartAnalysis{ChartsInfo: []ChartInfo{}},
+	}
+}
 
-		chartInfo := ChartInfo{
-			Name:           chartName,
-			Repository:     repository,
-			VersionCount:   len(versions),
-			Versions:       make([]string, 0, len(versions)),
-			VersionDetails: make([]VersionDetail, 0, len(versions)),
-		}
+// SetFilter restricts the analysis to the charts and versions passing f
+func (b *AnalysisBuilder) SetFilter(f *ChartFilter) {
+	b.filter = f
+}
 
-		var dates []time.Time
-		for _, version := range versions {
-			analysis.TotalVersions++
-			chartInfo.Versions = append(chartInfo.Versions, version.Version)
+// AddChart adds the versions of a single index entry that pass the builder's filter to the
+// analysis. It returns those versions, and false if the chart was filtered out.
+func (b *AnalysisBuilder) AddChart(chartName string, versions []ChartVersionInfo) ([]ChartVersionInfo, bool) {
+	analysis := b.analysis
 
-			// Get the first URL if available and resolve it
-			url := ""
-			if len(version.URLs) > 0 {
-				url = resolveChartURL(version.URLs[0], repoURL)
-			}
+	versions, ok := b.filter.Filter(chartName, versions)
+	if !ok {
+		analysis.FilteredCharts++
+		return nil, false
+	}
+	analysis.TotalCharts++
+
+	if len(versions) == 0 {
+		return versions, true
+	}
 
-			chartInfo.VersionDetails = append(chartInfo.VersionDetails, VersionDetail{
-				Version: version.Version,
-				Created: version.Created,
-				URL:     url,
-			})
+	// Chart metadata comes from the latest version, wherever the index lists it
+	latest := latestEntry(versions)
+	chartInfo := ChartInfo{
+		Name:           chartName,
+		Repository:     b.repository,
+		RepositoryURL:  repositoryBaseURL(b.repoURL),
+		VersionCount:   len(versions),
+		Versions:       make([]string, 0, len(versions)),
+		VersionDetails: make([]VersionDetail, 0, len(versions)),
+		Deprecated:     latest.Deprecated,
+		Dependencies:   latest.Dependencies,
+		AppVersion:     latest.AppVersion,
+		Type:           latest.Type,
+		Home:           latest.Home,
+		Sources:        latest.Sources,
+		Keywords:       latest.Keywords,
+		Maintainers:    latest.Maintainers,
+		Icon:           latest.Icon,
+		Description:    latest.Description,
+	}
+	if chartInfo.Deprecated {
+		analysis.DeprecatedCharts++
+	}
 
-			if !version.Created.IsZero() {
-				dates = append(dates, version.Created)
-				allDates = append(allDates, version.Created)
-			}
+	var dates []time.Time
+	for _, version := range versions {
+		analysis.TotalVersions++
+		chartInfo.Versions = append(chartInfo.Versions, version.Version)
 
-			// Capture icon and description from the latest version
-			if chartInfo.Icon == "" && version.Icon != "" {
-				chartInfo.Icon = version.Icon
-			}
-			if chartInfo.Description == "" && version.Description != "" {
-				chartInfo.Description = version.Description
-			}
+		// Get the first URL if available and resolve it
+		url := ""
+		if len(version.URLs) > 0 {
+			url = resolveChartURL(version.URLs[0], b.repoURL)
 		}
 
-		if len(dates) > 0 {
-			sort.Slice(dates, func(i, j int) bool {
-				return dates[i].Before(dates[j])
-			})
-			chartInfo.OldestVersion = dates[0]
-			chartInfo.NewestVersion = dates[len(dates)-1]
-			chartInfo.MedianVersion = dates[len(dates)/2]
+		chartInfo.VersionDetails = append(chartInfo.VersionDetails, VersionDetail{
+			Version:    version.Version,
+			AppVersion: version.AppVersion,
+			Created:    version.Created,
+			URL:        url,
+			Digest:     version.Digest,
+		})
+
+		if !version.Created.IsZero() {
+			dates = append(dates, version.Created)
+			b.allDates = append(b.allDates, version.Created)
 		}
 
-		analysis.ChartsInfo = append(analysis.ChartsInfo, chartInfo)
+		// Fall back to the icon and description of other versions
+		if chartInfo.Icon == "" && version.Icon != "" {
+			chartInfo.Icon = version.Icon
+		}
+		if chartInfo.Description == "" && version.Description != "" {
+			chartInfo.Description = version.Description
+		}
 	}
 
-	// Calculate overall dates
-	if len(allDates) > 0 {
-		sort.Slice(allDates, func(i, j int) bool {
-			return allDates[i].Before(allDates[j])
+	if len(dates) > 0 {
+		sort.Slice(dates, func(i, j int) bool {
+			return dates[i].Before(dates[j])
 		})
-		analysis.OldestChartDate = allDates[0]
-		analysis.NewestChartDate = allDates[len(allDates)-1]
-		analysis.MedianChartDate = allDates[len(allDates)/2]
+		chartInfo.OldestVersion = dates[0]
+		chartInfo.NewestVersion = dates[len(dates)-1]
+		chartInfo.MedianVersion = dates[len(dates)/2]
+	}
+
+	analysis.ChartsInfo = append(analysis.ChartsInfo, chartInfo)
+	return versions, true
+}
+
+// latestEntry returns the entry of the highest version, preferring stable releases as
+// LatestVersion does, or the first entry when no version is valid semver
+func latestEntry(versions []ChartVersionInfo) ChartVersionInfo {
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = v.Version
 	}
+	if latest := LatestVersion(names); latest != nil {
+		for _, v := range versions {
+			if sv, err := semver.NewVersion(v.Version); err == nil && sv.Equal(latest) {
+				return v
+			}
+		}
+	}
+	return versions[0]
+}
+
+// Analysis computes the overall statistics and returns the finished analysis
+func (b *AnalysisBuilder) Analysis() *ChartAnalysis {
+	analysis := b.analysis
+
+	// Calculate overall dates
+	sort.Slice(b.allDates, func(i, j int) bool {
+		return b.allDates[i].Before(b.allDates[j])
+	})
+	analysis.setReleaseDates(b.allDates)
 
 	// Sort charts by name for consistent output
 	sort.Slice(analysis.ChartsInfo, func(i, j int) bool {
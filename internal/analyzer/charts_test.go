@@ -0,0 +1,38 @@
+package analyzer
+
+import "testing"
+
+func TestAnalyzeCharts_UnsortedIndex(t *testing.T) {
+	// Hand-written and merged indexes don't always list the newest version first
+	index := &HelmIndex{Entries: map[string][]ChartVersionInfo{
+		"app": {
+			{Name: "app", Version: "1.0.0", AppVersion: "1.0", Deprecated: true, Description: "Old description"},
+			{Name: "app", Version: "2.0.0-rc.1", AppVersion: "2.0-rc"},
+			{Name: "app", Version: "1.2.0", AppVersion: "1.2", Description: "Current description",
+				Dependencies: []Dependency{{Name: "db", Version: "^1.0.0"}}},
+			{Name: "app", Version: "1.1.0", AppVersion: "1.1"},
+		},
+		"tool": {
+			{Name: "tool", Version: "nightly", AppVersion: "edge"},
+			{Name: "tool", Version: "snapshot", AppVersion: "old"},
+		},
+	}}
+
+	analysis := AnalyzeChartsWithRepo(index, "stable", "https://charts.example.com/index.yaml")
+	charts := make(map[string]ChartInfo)
+	for _, chart := range analysis.ChartsInfo {
+		charts[chart.Name] = chart
+	}
+
+	app := charts["app"]
+	if app.AppVersion != "1.2" || app.Deprecated || app.Description != "Current description" || len(app.Dependencies) != 1 {
+		t.Errorf("Expected metadata of the latest stable version 1.2.0, got %+v", app)
+	}
+	if analysis.DeprecatedCharts != 0 {
+		t.Errorf("Expected no deprecated charts, got %d", analysis.DeprecatedCharts)
+	}
+	// Without valid semver versions the first entry is used
+	if tool := charts["tool"]; tool.AppVersion != "edge" {
+		t.Errorf("Expected metadata of the first entry, got %+v", tool)
+	}
+}
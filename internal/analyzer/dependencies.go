@@ -0,0 +1,306 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// DependencyStatus describes how a chart dependency resolves against the scraped repositories
+type DependencyStatus string
+
+const (
+	// DependencyResolved means the constraint is satisfied by the latest version of the dependency
+	DependencyResolved DependencyStatus = "resolved"
+	// DependencyOutdated means the constraint is satisfiable but excludes the latest version
+	DependencyOutdated DependencyStatus = "outdated"
+	// DependencyUnresolvable means the repository is scraped but no version satisfies the constraint
+	DependencyUnresolvable DependencyStatus = "unresolvable"
+	// DependencyExternal means the dependency lives in a repository the exporter does not scrape
+	DependencyExternal DependencyStatus = "external"
+	// DependencyLocal means the dependency is vendored in the chart (file:// or no repository)
+	DependencyLocal DependencyStatus = "local"
+)
+
+// RepositoryAnalysis pairs a repository's configuration with its latest analysis
+type RepositoryAnalysis struct {
+	Name     string
+	URL      string
+	Analysis *ChartAnalysis
+}
+
+// DependencyNode is a chart in the dependency graph
+type DependencyNode struct {
+	ID         string `json:"id"`
+	Repository string `json:"repository"`
+	Chart      string `json:"chart"`
+	Version    string `json:"version,omitempty"`
+	External   bool   `json:"external,omitempty"`
+}
+
+// DependencyEdge links a dependent chart to one of its dependencies
+type DependencyEdge struct {
+	From               string           `json:"from"`
+	To                 string           `json:"to"`
+	Repository         string           `json:"repository"`
+	Chart              string           `json:"chart"`
+	Version            string           `json:"version"`
+	Dependency         Dependency       `json:"dependency"`
+	ResolvedRepository string           `json:"resolvedRepository,omitempty"`
+	ResolvedVersion    string           `json:"resolvedVersion,omitempty"`
+	LatestVersion      string           `json:"latestVersion,omitempty"`
+	Status             DependencyStatus `json:"status"`
+}
+
+// DependencyGraph is the graph of chart dependencies across all scraped repositories
+type DependencyGraph struct {
+	Nodes []DependencyNode `json:"nodes"`
+	Edges []DependencyEdge `json:"edges"`
+}
+
+// BuildDependencyGraph resolves the dependencies of the latest version of every chart
+// against the given repositories. Dependency repositories are matched by URL, or by
+// name when referenced as "@name" or "alias:name" like `helm dependency update` does.
+func BuildDependencyGraph(repos []RepositoryAnalysis) *DependencyGraph {
+	byName := make(map[string]*RepositoryAnalysis, len(repos))
+	byURL := make(map[string]*RepositoryAnalysis, len(repos))
+	for i := range repos {
+		byName[repos[i].Name] = &repos[i]
+		byURL[normalizeRepoURL(repos[i].URL)] = &repos[i]
+	}
+
+	graph := &DependencyGraph{Nodes: []DependencyNode{}, Edges: []DependencyEdge{}}
+	nodes := make(map[string]DependencyNode)
+
+	for _, repo := range repos {
+		if repo.Analysis == nil {
+			continue
+		}
+		for _, chart := range repo.Analysis.ChartsInfo {
+			if len(chart.Dependencies) == 0 {
+				continue
+			}
+
+			version := ""
+			if len(chart.Versions) > 0 {
+				version = chart.Versions[0]
+			}
+			from := nodeID(repo.Name, chart.Name)
+			nodes[from] = DependencyNode{ID: from, Repository: repo.Name, Chart: chart.Name, Version: version}
+
+			for _, dep := range chart.Dependencies {
+				edge := DependencyEdge{
+					From:       from,
+					Repository: repo.Name,
+					Chart:      chart.Name,
+					Version:    version,
+					Dependency: dep,
+				}
+
+				target := lookupRepository(dep.Repository, byName, byURL)
+				switch {
+				case dep.Repository == "" || strings.HasPrefix(dep.Repository, "file://"):
+					edge.Status = DependencyLocal
+				case target == nil || target.Analysis == nil:
+					edge.Status = DependencyExternal
+				default:
+					edge.ResolvedRepository = target.Name
+					resolveDependency(&edge, target.Analysis)
+				}
+
+				if edge.ResolvedRepository != "" {
+					edge.To = nodeID(edge.ResolvedRepository, dep.Name)
+					if _, ok := nodes[edge.To]; !ok {
+						nodes[edge.To] = DependencyNode{ID: edge.To, Repository: edge.ResolvedRepository, Chart: dep.Name, Version: edge.LatestVersion}
+					}
+				} else {
+					edge.To = nodeID(dep.Repository, dep.Name)
+					if _, ok := nodes[edge.To]; !ok {
+						nodes[edge.To] = DependencyNode{ID: edge.To, Repository: dep.Repository, Chart: dep.Name, External: true}
+					}
+				}
+
+				graph.Edges = append(graph.Edges, edge)
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].ID < graph.Nodes[j].ID
+	})
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	return graph
+}
+
+// StatusCounts returns the number of dependencies per status for every dependent chart,
+// keyed by repository and chart name
+func (g *DependencyGraph) StatusCounts() map[[2]string]map[DependencyStatus]int {
+	counts := make(map[[2]string]map[DependencyStatus]int)
+	for _, edge := range g.Edges {
+		key := [2]string{edge.Repository, edge.Chart}
+		if counts[key] == nil {
+			counts[key] = make(map[DependencyStatus]int)
+		}
+		counts[key][edge.Status]++
+	}
+	return counts
+}
+
+// WriteDOT renders the graph in Graphviz DOT format. Unresolvable dependencies are
+// drawn in red, outdated ones in orange and external ones dashed.
+func (g *DependencyGraph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph dependencies {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=box];")
+	for _, node := range g.Nodes {
+		label := node.ID
+		if node.Version != "" {
+			label += "\n" + node.Version
+		}
+		style := ""
+		if node.External {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(bw, "  %q [label=%q%s];\n", node.ID, label, style)
+	}
+	for _, edge := range g.Edges {
+		attrs := fmt.Sprintf("label=%q", edge.Dependency.Version)
+		switch edge.Status {
+		case DependencyUnresolvable:
+			attrs += ", color=red"
+		case DependencyOutdated:
+			attrs += ", color=orange"
+		case DependencyExternal, DependencyLocal:
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(bw, "  %q -> %q [%s];\n", edge.From, edge.To, attrs)
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// resolveDependency finds the highest version of the dependency satisfying its constraint
+func resolveDependency(edge *DependencyEdge, analysis *ChartAnalysis) {
+	var chart *ChartInfo
+	for i := range analysis.ChartsInfo {
+		if analysis.ChartsInfo[i].Name == edge.Dependency.Name {
+			chart = &analysis.ChartsInfo[i]
+			break
+		}
+	}
+	if chart == nil {
+		edge.Status = DependencyUnresolvable
+		return
+	}
+
+	latest := LatestVersion(chart.Versions)
+	if latest != nil {
+		edge.LatestVersion = latest.Original()
+	}
+
+	constraintText := edge.Dependency.Version
+	if constraintText == "" {
+		constraintText = "*"
+	}
+	constraint, err := semver.NewConstraint(constraintText)
+	if err != nil {
+		edge.Status = DependencyUnresolvable
+		return
+	}
+
+	var best *semver.Version
+	for _, v := range chart.Versions {
+		parsed, err := semver.NewVersion(v)
+		if err != nil || !constraint.Check(parsed) {
+			continue
+		}
+		if best == nil || parsed.GreaterThan(best) {
+			best = parsed
+		}
+	}
+
+	if best == nil {
+		edge.Status = DependencyUnresolvable
+		return
+	}
+
+	edge.ResolvedVersion = best.Original()
+	if best.LessThan(latest) {
+		edge.Status = DependencyOutdated
+	} else {
+		edge.Status = DependencyResolved
+	}
+}
+
+// LatestVersion returns the highest stable semantic version in the list, falling back to
+// the highest pre-release if there is no stable one. Invalid versions are ignored.
+func LatestVersion(versions []string) *semver.Version {
+	var latest, latestPre *semver.Version
+	for _, v := range versions {
+		parsed, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if parsed.Prerelease() != "" {
+			if latestPre == nil || parsed.GreaterThan(latestPre) {
+				latestPre = parsed
+			}
+			continue
+		}
+		if latest == nil || parsed.GreaterThan(latest) {
+			latest = parsed
+		}
+	}
+	if latest == nil {
+		return latestPre
+	}
+	return latest
+}
+
+// lookupRepository finds the scraped repository a dependency refers to
+func lookupRepository(ref string, byName, byURL map[string]*RepositoryAnalysis) *RepositoryAnalysis {
+	switch {
+	case strings.HasPrefix(ref, "@"):
+		return byName[strings.TrimPrefix(ref, "@")]
+	case strings.HasPrefix(ref, "alias:"):
+		return byName[strings.TrimPrefix(ref, "alias:")]
+	default:
+		return byURL[normalizeRepoURL(ref)]
+	}
+}
+
+// normalizeRepoURL reduces a repository or index file URL to a comparable base URL,
+// without credentials
+func normalizeRepoURL(repoURL string) string {
+	base := repositoryBaseURL(repoURL)
+	if u, err := url.Parse(base); err == nil && u.User != nil {
+		u.User = nil
+		base = u.String()
+	}
+	return strings.ToLower(strings.TrimSuffix(base, "/"))
+}
+
+// nodeID builds the graph identifier of a chart
+func nodeID(repository, chart string) string {
+	if repository == "" {
+		return chart
+	}
+	return repository + "/" + chart
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewVersion is a chart version that appeared in a repository since an earlier scrape
+type NewVersion struct {
+	Repository string    `json:"repository"`
+	Chart      string    `json:"chart"`
+	Version    string    `json:"version"`
+	Created    time.Time `json:"created"`
+	URL        string    `json:"url,omitempty"`
+	NewChart   bool      `json:"newChart"` // The chart had no versions before
+}
+
+// VersionTracker remembers every chart version seen in each repository, so that versions
+// that disappear and come back, for example when a repository switches between its
+// published and generated index, are not reported as new again
+type VersionTracker struct {
+	mu     sync.Mutex
+	seen   map[versionKey]bool
+	charts map[versionKey]bool // Keyed by repository and chart, without version
+	repos  map[string]bool     // Repositories whose first analysis was recorded
+}
+
+type versionKey struct {
+	repository, chart, version string
+}
+
+// NewVersionTracker creates an empty tracker
+func NewVersionTracker() *VersionTracker {
+	return &VersionTracker{
+		seen:   make(map[versionKey]bool),
+		charts: make(map[versionKey]bool),
+		repos:  make(map[string]bool),
+	}
+}
+
+// Observe records the chart versions of a repository's analysis and returns those never
+// seen before in that repository, sorted by chart and then newest first. The first
+// analysis of a repository is only recorded, so that it does not report every version.
+func (t *VersionTracker) Observe(repository string, analysis *ChartAnalysis) []NewVersion {
+	if analysis == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	first := !t.repos[repository]
+	t.repos[repository] = true
+
+	var added []NewVersion
+	for _, chart := range analysis.ChartsInfo {
+		chartKey := versionKey{repository: repository, chart: chart.Name}
+		existed := t.charts[chartKey]
+		t.charts[chartKey] = true
+		for _, detail := range chart.VersionDetails {
+			key := versionKey{repository: repository, chart: chart.Name, version: detail.Version}
+			if t.seen[key] {
+				continue
+			}
+			t.seen[key] = true
+			if first {
+				continue
+			}
+			added = append(added, NewVersion{
+				Repository: repository,
+				Chart:      chart.Name,
+				Version:    detail.Version,
+				Created:    detail.Created,
+				URL:        detail.URL,
+				NewChart:   !existed,
+			})
+		}
+	}
+
+	sort.SliceStable(added, func(i, j int) bool {
+		if added[i].Chart != added[j].Chart {
+			return added[i].Chart < added[j].Chart
+		}
+		return added[i].Created.After(added[j].Created)
+	})
+	return added
+}
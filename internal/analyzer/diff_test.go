@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVersionTracker_Observe(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	analyze := func(entries map[string][]ChartVersionInfo) *ChartAnalysis {
+		return AnalyzeChartsWithRepo(&HelmIndex{Entries: entries}, "stable", "https://charts.example.com/index.yaml")
+	}
+	previous := analyze(map[string][]ChartVersionInfo{
+		"app": {{Name: "app", Version: "1.0.0", Created: day(1)}},
+	})
+	current := analyze(map[string][]ChartVersionInfo{
+		"app": {
+			{Name: "app", Version: "1.2.0", Created: day(3)},
+			{Name: "app", Version: "1.1.0", Created: day(2)},
+			{Name: "app", Version: "1.0.0", Created: day(1)},
+		},
+		"db": {{Name: "db", Version: "0.1.0", Created: day(2)}},
+	})
+
+	tracker := NewVersionTracker()
+	if got := tracker.Observe("stable", previous); got != nil {
+		t.Errorf("Expected no new versions for the first analysis, got %v", got)
+	}
+
+	got := tracker.Observe("stable", current)
+	expected := []NewVersion{
+		{Chart: "app", Version: "1.2.0"},
+		{Chart: "app", Version: "1.1.0"},
+		{Chart: "db", Version: "0.1.0", NewChart: true},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d new versions, got %v", len(expected), got)
+	}
+	for i, e := range expected {
+		if got[i].Chart != e.Chart || got[i].Version != e.Version || got[i].NewChart != e.NewChart || got[i].Repository != "stable" {
+			t.Errorf("Version %d: expected %+v, got %+v", i, e, got[i])
+		}
+	}
+
+	// Versions that disappear and come back, as when a repository falls back to a generated
+	// index that lacks some versions and then recovers, are not reported again
+	if got := tracker.Observe("stable", previous); got != nil {
+		t.Errorf("Expected no new versions when versions disappear, got %v", got)
+	}
+	if got := tracker.Observe("stable", current); got != nil {
+		t.Errorf("Expected reappearing versions to be ignored, got %v", got)
+	}
+
+	// Other repositories are tracked separately
+	if got := tracker.Observe("mirror", previous); got != nil {
+		t.Errorf("Expected the first analysis of another repository to be recorded only, got %v", got)
+	}
+	if got := tracker.Observe("mirror", current); len(got) != 3 || got[0].Repository != "mirror" {
+		t.Errorf("Expected 3 new versions in the mirror, got %v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ChartFilter selects the charts and versions of a repository that are analyzed
+type ChartFilter struct {
+	include     []func(string) bool
+	exclude     []func(string) bool
+	constraints *semver.Constraints
+}
+
+// NewChartFilter creates a filter from include and exclude patterns and a semver constraint.
+// Patterns are globs (nginx-*) or, when wrapped in slashes, regular expressions (/^nginx-.*$/).
+// A chart is kept if it matches any include pattern (or there are none) and no exclude
+// pattern. When versions is set, only versions satisfying it are kept and charts left
+// without versions are dropped.
+func NewChartFilter(include, exclude []string, versions string) (*ChartFilter, error) {
+	f := &ChartFilter{}
+	var err error
+	if f.include, err = compilePatterns(include); err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	if f.exclude, err = compilePatterns(exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	if versions != "" {
+		if f.constraints, err = semver.NewConstraint(versions); err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", versions, err)
+		}
+	}
+	return f, nil
+}
+
+func compilePatterns(patterns []string) ([]func(string) bool, error) {
+	matchers := make([]func(string) bool, 0, len(patterns))
+	for _, pattern := range patterns {
+		if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%q: %w", pattern, err)
+		}
+		pattern := pattern
+		matchers = append(matchers, func(name string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		})
+	}
+	return matchers, nil
+}
+
+// Filter returns the versions of a chart that pass the filter, and false if the whole
+// chart is filtered out. The versions slice is not modified.
+func (f *ChartFilter) Filter(chartName string, versions []ChartVersionInfo) ([]ChartVersionInfo, bool) {
+	if f == nil {
+		return versions, true
+	}
+	if len(f.include) > 0 && !matchAny(f.include, chartName) {
+		return nil, false
+	}
+	if matchAny(f.exclude, chartName) {
+		return nil, false
+	}
+	if f.constraints == nil || len(versions) == 0 {
+		return versions, true
+	}
+
+	kept := make([]ChartVersionInfo, 0, len(versions))
+	for _, version := range versions {
+		if sv, err := semver.NewVersion(version.Version); err == nil && f.constraints.Check(sv) {
+			kept = append(kept, version)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, false
+	}
+	return kept, true
+}
+
+// KeepsVersion reports whether a single version of a chart passes the filter
+func (f *ChartFilter) KeepsVersion(chartName, version string) bool {
+	_, ok := f.Filter(chartName, []ChartVersionInfo{{Version: version}})
+	return ok
+}
+
+func matchAny(matchers []func(string) bool, name string) bool {
+	for _, match := range matchers {
+		if match(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestChartFilter(t *testing.T) {
+	f, err := NewChartFilter([]string{"nginx*", "/^redis(-cluster)?$/"}, []string{"nginx-legacy"}, ">= 1.0.0")
+	if err != nil {
+		t.Fatalf("NewChartFilter failed: %v", err)
+	}
+
+	versions := []ChartVersionInfo{{Version: "1.2.0"}, {Version: "1.0.0"}, {Version: "0.9.0"}, {Version: "not-semver"}}
+	tests := []struct {
+		chart    string
+		versions []ChartVersionInfo
+		kept     int
+		ok       bool
+	}{
+		{chart: "nginx", versions: versions, kept: 2, ok: true},
+		{chart: "nginx-ingress", versions: versions, kept: 2, ok: true},
+		{chart: "nginx-legacy", versions: versions, ok: false},
+		{chart: "redis-cluster", versions: versions, kept: 2, ok: true},
+		{chart: "redis-sentinel", versions: versions, ok: false},
+		{chart: "postgresql", versions: versions, ok: false},
+		{chart: "nginx", versions: []ChartVersionInfo{{Version: "0.1.0"}}, ok: false},
+	}
+	for _, tt := range tests {
+		kept, ok := f.Filter(tt.chart, tt.versions)
+		if ok != tt.ok || len(kept) != tt.kept {
+			t.Errorf("%s: expected %d version(s) kept=%v, got %d kept=%v", tt.chart, tt.kept, tt.ok, len(kept), ok)
+		}
+	}
+
+	// A nil filter keeps everything
+	var none *ChartFilter
+	if kept, ok := none.Filter("anything", versions); !ok || len(kept) != len(versions) {
+		t.Error("Nil filter dropped versions")
+	}
+
+	for _, bad := range [][3]string{{"[", "", ""}, {"", "/(/", ""}, {"", "", "not a constraint"}} {
+		var include, exclude []string
+		if bad[0] != "" {
+			include = []string{bad[0]}
+		}
+		if bad[1] != "" {
+			exclude = []string{bad[1]}
+		}
+		if _, err := NewChartFilter(include, exclude, bad[2]); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestAnalysisBuilder_Filter(t *testing.T) {
+	f, err := NewChartFilter(nil, []string{"skip-*"}, "")
+	if err != nil {
+		t.Fatalf("NewChartFilter failed: %v", err)
+	}
+
+	builder := NewAnalysisBuilder("repo", "")
+	builder.SetFilter(f)
+	builder.AddChart("keep", []ChartVersionInfo{{Name: "keep", Version: "1.0.0"}})
+	builder.AddChart("skip-me", []ChartVersionInfo{{Name: "skip-me", Version: "1.0.0"}})
+	builder.AddChart("skip-too", []ChartVersionInfo{{Name: "skip-too", Version: "1.0.0"}})
+
+	analysis := builder.Analysis()
+	if analysis.TotalCharts != 1 || analysis.TotalVersions != 1 || analysis.FilteredCharts != 2 {
+		t.Errorf("Expected 1 chart, 1 version and 2 filtered, got %d, %d and %d", analysis.TotalCharts, analysis.TotalVersions, analysis.FilteredCharts)
+	}
+}
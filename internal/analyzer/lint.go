@@ -0,0 +1,318 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// LintSeverity is the severity of a lint violation
+type LintSeverity string
+
+const (
+	// LintError marks violations that break `helm repo add` or `helm install`
+	LintError LintSeverity = "error"
+	// LintWarning marks violations of Helm repository conventions
+	LintWarning LintSeverity = "warning"
+	// LintInfo marks purely informational findings
+	LintInfo LintSeverity = "info"
+)
+
+// ParseLintSeverity validates a severity name
+func ParseLintSeverity(s string) (LintSeverity, error) {
+	switch sev := LintSeverity(strings.ToLower(s)); sev {
+	case LintError, LintWarning, LintInfo:
+		return sev, nil
+	default:
+		return "", fmt.Errorf("unknown lint severity %q (expected error, warning or info)", s)
+	}
+}
+
+// LintViolation is a single finding reported by a lint rule
+type LintViolation struct {
+	Repository string
+	Rule       string
+	Severity   LintSeverity
+	Chart      string
+	Version    string
+	Message    string
+}
+
+// LintInput is what lint rules inspect. Index-scoped rules see the index metadata;
+// entry-scoped rules see an index holding the single entry being checked.
+type LintInput struct {
+	Index   *HelmIndex
+	RepoURL string
+	Now     time.Time
+}
+
+// LintScope selects what a rule is run against
+type LintScope int
+
+const (
+	// LintScopeEntry rules run once per chart entry
+	LintScopeEntry LintScope = iota
+	// LintScopeIndex rules run once per index, against its top-level fields
+	LintScopeIndex
+)
+
+// LintFinding is what a rule reports; the linter fills in repository, rule and severity
+type LintFinding struct {
+	Chart   string
+	Version string
+	Message string
+}
+
+// LintRule is a single index check. Rules are plain values so callers can add their own
+// to the list passed to NewLinter.
+type LintRule struct {
+	Name     string
+	Severity LintSeverity
+	Scope    LintScope
+	Check    func(input LintInput) []LintFinding
+}
+
+// DefaultLintRules returns the built-in checks for Helm repository conventions
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		{Name: "missing-api-version", Severity: LintError, Scope: LintScopeIndex, Check: lintMissingAPIVersion},
+		{Name: "generated-in-future", Severity: LintWarning, Scope: LintScopeIndex, Check: lintGeneratedInFuture},
+		{Name: "name-mismatch", Severity: LintError, Check: lintNameMismatch},
+		{Name: "duplicate-version", Severity: LintError, Check: lintDuplicateVersion},
+		{Name: "invalid-semver", Severity: LintError, Check: lintInvalidSemver},
+		{Name: "missing-created", Severity: LintWarning, Check: lintMissingCreated},
+		{Name: "empty-urls", Severity: LintError, Check: lintEmptyURLs},
+		{Name: "unresolvable-url", Severity: LintError, Check: lintUnresolvableURL},
+	}
+}
+
+// Linter runs a set of lint rules against Helm indexes
+type Linter struct {
+	rules []LintRule
+}
+
+// NewLinter creates a linter from the given rules, applying severity overrides and
+// dropping suppressed rules. Unknown rule names in either list are an error.
+func NewLinter(rules []LintRule, severities map[string]string, suppressed []string) (*Linter, error) {
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.Name] = true
+	}
+
+	skip := make(map[string]bool, len(suppressed))
+	for _, name := range suppressed {
+		if !known[name] {
+			return nil, fmt.Errorf("cannot suppress unknown lint rule %q", name)
+		}
+		skip[name] = true
+	}
+	for name := range severities {
+		if !known[name] {
+			return nil, fmt.Errorf("cannot set severity of unknown lint rule %q", name)
+		}
+	}
+
+	linter := &Linter{}
+	for _, rule := range rules {
+		if skip[rule.Name] {
+			continue
+		}
+		if override, ok := severities[rule.Name]; ok {
+			severity, err := ParseLintSeverity(override)
+			if err != nil {
+				return nil, fmt.Errorf("lint rule %q: %w", rule.Name, err)
+			}
+			rule.Severity = severity
+		}
+		linter.rules = append(linter.rules, rule)
+	}
+
+	return linter, nil
+}
+
+// Rules returns the active rules after suppression and severity overrides
+func (l *Linter) Rules() []LintRule {
+	return l.rules
+}
+
+// Lint runs every active rule against the index
+func (l *Linter) Lint(index *HelmIndex, repository, repoURL string) []LintViolation {
+	violations := l.LintIndex(index, repository, repoURL)
+	for _, chartName := range index.ChartNames() {
+		violations = append(violations, l.LintEntry(chartName, index.Entries[chartName], repository, repoURL)...)
+	}
+	return violations
+}
+
+// LintIndex runs the index-scoped rules against the index metadata
+func (l *Linter) LintIndex(index *HelmIndex, repository, repoURL string) []LintViolation {
+	input := LintInput{Index: index, RepoURL: repoURL, Now: time.Now()}
+	return l.run(LintScopeIndex, input, repository)
+}
+
+// LintEntry runs the entry-scoped rules against the versions of a single chart,
+// which lets an index be linted while it is walked with WalkIndex
+func (l *Linter) LintEntry(chartName string, versions []ChartVersionInfo, repository, repoURL string) []LintViolation {
+	input := LintInput{
+		Index:   &HelmIndex{Entries: map[string][]ChartVersionInfo{chartName: versions}},
+		RepoURL: repoURL,
+		Now:     time.Now(),
+	}
+	return l.run(LintScopeEntry, input, repository)
+}
+
+func (l *Linter) run(scope LintScope, input LintInput, repository string) []LintViolation {
+	violations := []LintViolation{}
+	for _, rule := range l.rules {
+		if rule.Scope != scope {
+			continue
+		}
+		for _, finding := range rule.Check(input) {
+			violations = append(violations, LintViolation{
+				Repository: repository,
+				Rule:       rule.Name,
+				Severity:   rule.Severity,
+				Chart:      finding.Chart,
+				Version:    finding.Version,
+				Message:    finding.Message,
+			})
+		}
+	}
+	return violations
+}
+
+// forEachVersion calls fn for every chart version in a deterministic order
+func forEachVersion(index *HelmIndex, fn func(entry string, version ChartVersionInfo)) {
+	for _, name := range index.ChartNames() {
+		for _, version := range index.Entries[name] {
+			fn(name, version)
+		}
+	}
+}
+
+func lintMissingAPIVersion(input LintInput) []LintFinding {
+	if input.Index.APIVersion != "" {
+		return nil
+	}
+	return []LintFinding{{Message: "index has no apiVersion"}}
+}
+
+func lintGeneratedInFuture(input LintInput) []LintFinding {
+	// Allow for a little clock skew between the generator and the exporter
+	if !input.Index.Generated.After(input.Now.Add(5 * time.Minute)) {
+		return nil
+	}
+	return []LintFinding{{Message: fmt.Sprintf("generated timestamp %s is in the future", input.Index.Generated.Format(time.RFC3339))}}
+}
+
+func lintNameMismatch(input LintInput) []LintFinding {
+	var findings []LintFinding
+	forEachVersion(input.Index, func(entry string, version ChartVersionInfo) {
+		if version.Name != entry {
+			findings = append(findings, LintFinding{
+				Chart:   entry,
+				Version: version.Version,
+				Message: fmt.Sprintf("entry %q contains chart named %q", entry, version.Name),
+			})
+		}
+	})
+	return findings
+}
+
+func lintDuplicateVersion(input LintInput) []LintFinding {
+	var findings []LintFinding
+	for _, entry := range input.Index.ChartNames() {
+		seen := make(map[string]bool)
+		reported := make(map[string]bool)
+		for _, version := range input.Index.Entries[entry] {
+			if seen[version.Version] && !reported[version.Version] {
+				findings = append(findings, LintFinding{
+					Chart:   entry,
+					Version: version.Version,
+					Message: fmt.Sprintf("version %s is listed more than once", version.Version),
+				})
+				reported[version.Version] = true
+			}
+			seen[version.Version] = true
+		}
+	}
+	return findings
+}
+
+func lintInvalidSemver(input LintInput) []LintFinding {
+	var findings []LintFinding
+	forEachVersion(input.Index, func(entry string, version ChartVersionInfo) {
+		if _, err := semver.NewVersion(version.Version); err != nil {
+			findings = append(findings, LintFinding{
+				Chart:   entry,
+				Version: version.Version,
+				Message: fmt.Sprintf("version %q is not a valid semantic version", version.Version),
+			})
+		}
+	})
+	return findings
+}
+
+func lintMissingCreated(input LintInput) []LintFinding {
+	var findings []LintFinding
+	forEachVersion(input.Index, func(entry string, version ChartVersionInfo) {
+		if version.Created.IsZero() {
+			findings = append(findings, LintFinding{
+				Chart:   entry,
+				Version: version.Version,
+				Message: "version has no created timestamp",
+			})
+		}
+	})
+	return findings
+}
+
+func lintEmptyURLs(input LintInput) []LintFinding {
+	var findings []LintFinding
+	forEachVersion(input.Index, func(entry string, version ChartVersionInfo) {
+		if len(version.URLs) == 0 {
+			findings = append(findings, LintFinding{
+				Chart:   entry,
+				Version: version.Version,
+				Message: "version has no download URLs",
+			})
+		}
+	})
+	return findings
+}
+
+func lintUnresolvableURL(input LintInput) []LintFinding {
+	base, baseErr := url.Parse(repositoryBaseURL(input.RepoURL) + "/")
+
+	var findings []LintFinding
+	forEachVersion(input.Index, func(entry string, version ChartVersionInfo) {
+		for _, chartURL := range version.URLs {
+			ref, err := url.Parse(chartURL)
+			if err == nil && ref.IsAbs() {
+				continue
+			}
+
+			message := ""
+			switch {
+			case err != nil:
+				message = fmt.Sprintf("URL %q cannot be parsed: %v", chartURL, err)
+			case baseErr != nil || !base.IsAbs():
+				message = fmt.Sprintf("relative URL %q cannot be resolved against repository URL %q", chartURL, input.RepoURL)
+			case base.Scheme != "file" && base.ResolveReference(ref).Host == "":
+				message = fmt.Sprintf("relative URL %q does not resolve to a host", chartURL)
+			default:
+				continue
+			}
+
+			findings = append(findings, LintFinding{
+				Chart:   entry,
+				Version: version.Version,
+				Message: message,
+			})
+		}
+	})
+	return findings
+}
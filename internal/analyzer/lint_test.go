@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLinter_DefaultRules(t *testing.T) {
+	index := &HelmIndex{
+		Generated: time.Now().Add(24 * time.Hour),
+		Entries: map[string][]ChartVersionInfo{
+			"good": {
+				{Name: "good", Version: "1.0.0", Created: time.Now(), URLs: []string{"good-1.0.0.tgz"}},
+			},
+			"bad": {
+				{Name: "other", Version: "1.0.0", Created: time.Now(), URLs: []string{"https://example.com/bad-1.0.0.tgz"}},
+				{Name: "bad", Version: "1.0.0", Created: time.Now(), URLs: []string{"https://example.com/bad-1.0.0.tgz"}},
+				{Name: "bad", Version: "not-semver", URLs: []string{"%zz"}},
+				{Name: "bad", Version: "2.0.0", Created: time.Now()},
+			},
+		},
+	}
+
+	linter, err := NewLinter(DefaultLintRules(), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create linter: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, v := range linter.Lint(index, "test", "https://charts.example.com/index.yaml") {
+		counts[v.Rule]++
+		if v.Repository != "test" {
+			t.Errorf("Expected repository %q, got %q", "test", v.Repository)
+		}
+	}
+
+	expected := map[string]int{
+		"missing-api-version": 1,
+		"generated-in-future": 1,
+		"name-mismatch":       1,
+		"duplicate-version":   1,
+		"invalid-semver":      1,
+		"missing-created":     1,
+		"empty-urls":          1,
+		"unresolvable-url":    1,
+	}
+	for rule, count := range expected {
+		if counts[rule] != count {
+			t.Errorf("Rule %s: expected %d violation(s), got %d", rule, count, counts[rule])
+		}
+	}
+}
+
+func TestNewLinter_Overrides(t *testing.T) {
+	linter, err := NewLinter(DefaultLintRules(), map[string]string{"missing-created": "info"}, []string{"invalid-semver"})
+	if err != nil {
+		t.Fatalf("Failed to create linter: %v", err)
+	}
+
+	for _, rule := range linter.Rules() {
+		if rule.Name == "invalid-semver" {
+			t.Error("Suppressed rule is still active")
+		}
+		if rule.Name == "missing-created" && rule.Severity != LintInfo {
+			t.Errorf("Expected missing-created severity %q, got %q", LintInfo, rule.Severity)
+		}
+	}
+
+	if _, err := NewLinter(DefaultLintRules(), nil, []string{"no-such-rule"}); err == nil {
+		t.Error("Expected an error when suppressing an unknown rule")
+	}
+	if _, err := NewLinter(DefaultLintRules(), map[string]string{"empty-urls": "fatal"}, nil); err == nil {
+		t.Error("Expected an error for an unknown severity")
+	}
+}
+
+func TestLintUnresolvableURL_RepositoryURLs(t *testing.T) {
+	index := &HelmIndex{Entries: map[string][]ChartVersionInfo{
+		"app": {{Name: "app", Version: "1.0.0", URLs: []string{"charts/app-1.0.0.tgz"}}},
+	}}
+
+	tests := []struct {
+		repoURL  string
+		expected int
+	}{
+		{repoURL: "https://charts.example.com/index.yaml", expected: 0},
+		{repoURL: "https://charts.example.com/index.yaml.gz", expected: 0},
+		{repoURL: "file:///srv/charts/index.yaml", expected: 0},
+		{repoURL: "file:///srv/charts", expected: 0},
+		{repoURL: "", expected: 1},
+	}
+
+	for _, tt := range tests {
+		if got := len(lintUnresolvableURL(LintInput{Index: index, RepoURL: tt.repoURL})); got != tt.expected {
+			t.Errorf("%q: expected %d finding(s), got %d", tt.repoURL, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveChartURL_IndexFileNames(t *testing.T) {
+	for _, repoURL := range []string{
+		"https://charts.example.com/stable/index.yaml",
+		"https://charts.example.com/stable/index.yaml.gz",
+		"https://charts.example.com/stable/index.json",
+		"https://charts.example.com/stable/",
+	} {
+		if got := resolveChartURL("app-1.0.0.tgz", repoURL); got != "https://charts.example.com/stable/app-1.0.0.tgz" {
+			t.Errorf("%q: got %q", repoURL, got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+)
+
+// ReleaseQuantiles are the quantiles of version creation dates exported as metrics
+var ReleaseQuantiles = []float64{0.1, 0.5, 0.9}
+
+// DateQuantile returns the date below which a fraction q of the sorted dates lie, using
+// the same rank as MedianChartDate, or the zero time without dates
+func DateQuantile(dates []time.Time, q float64) time.Time {
+	if len(dates) == 0 {
+		return time.Time{}
+	}
+	i := int(q * float64(len(dates)))
+	return dates[max(0, min(i, len(dates)-1))]
+}
+
+// Quantile returns the quantile q of the analysis' version creation dates
+func (a *ChartAnalysis) Quantile(q float64) time.Time {
+	return DateQuantile(a.ReleaseDates, q)
+}
+
+// MergeReleaseDates merges the sorted release dates of several analyses into one sorted slice
+func MergeReleaseDates(analyses ...*ChartAnalysis) []time.Time {
+	var merged []time.Time
+	for _, analysis := range analyses {
+		if analysis != nil {
+			merged = mergeSortedDates(merged, analysis.ReleaseDates)
+		}
+	}
+	return merged
+}
+
+func mergeSortedDates(a, b []time.Time) []time.Time {
+	merged := make([]time.Time, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].Before(a[0]) {
+			merged, b = append(merged, b[0]), b[1:]
+		} else {
+			merged, a = append(merged, a[0]), a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// MergeAnalyses combines the analyses of several repositories into one. Date statistics are
+// computed from the merged release dates, so they are exact, and charts are sorted by name
+// and repository, so the result does not depend on the order of the analyses.
+func MergeAnalyses(analyses ...*ChartAnalysis) *ChartAnalysis {
+	merged := &ChartAnalysis{ChartsInfo: []ChartInfo{}}
+	for _, analysis := range analyses {
+		if analysis == nil {
+			continue
+		}
+		merged.TotalCharts += analysis.TotalCharts
+		merged.TotalVersions += analysis.TotalVersions
+		merged.DeprecatedCharts += analysis.DeprecatedCharts
+		merged.FilteredCharts += analysis.FilteredCharts
+		merged.IndexSize += analysis.IndexSize
+		merged.ChartsInfo = append(merged.ChartsInfo, analysis.ChartsInfo...)
+		merged.LintViolations = append(merged.LintViolations, analysis.LintViolations...)
+	}
+
+	sort.SliceStable(merged.ChartsInfo, func(i, j int) bool {
+		if merged.ChartsInfo[i].Name != merged.ChartsInfo[j].Name {
+			return merged.ChartsInfo[i].Name < merged.ChartsInfo[j].Name
+		}
+		return merged.ChartsInfo[i].Repository < merged.ChartsInfo[j].Repository
+	})
+
+	merged.setReleaseDates(MergeReleaseDates(analyses...))
+	return merged
+}
+
+// setReleaseDates stores the sorted release dates and the statistics derived from them
+func (a *ChartAnalysis) setReleaseDates(dates []time.Time) {
+	a.ReleaseDates = dates
+	if len(dates) == 0 {
+		return
+	}
+	a.OldestChartDate = dates[0]
+	a.NewestChartDate = dates[len(dates)-1]
+	a.MedianChartDate = DateQuantile(dates, 0.5)
+}
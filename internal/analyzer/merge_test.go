@@ -0,0 +1,73 @@
+package analyzer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMergeAnalyses(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	versions := func(name string, days ...int) []ChartVersionInfo {
+		var v []ChartVersionInfo
+		for i, d := range days {
+			v = append(v, ChartVersionInfo{Name: name, Version: fmt.Sprintf("1.%d.0", i), Created: day(d)})
+		}
+		return v
+	}
+	small := AnalyzeChartsWithRepo(&HelmIndex{Entries: map[string][]ChartVersionInfo{
+		"app": versions("app", 1, 2, 3),
+	}}, "small", "https://small.example.com/index.yaml")
+	large := AnalyzeChartsWithRepo(&HelmIndex{Entries: map[string][]ChartVersionInfo{
+		"app": versions("app", 10, 11, 12, 13, 14),
+		"db":  versions("db", 15, 16, 17, 18, 19),
+	}}, "large", "https://large.example.com/index.yaml")
+
+	merged := MergeAnalyses(large, nil, small)
+	if merged.TotalCharts != 3 || merged.TotalVersions != 13 {
+		t.Errorf("Expected 3 charts and 13 versions, got %d and %d", merged.TotalCharts, merged.TotalVersions)
+	}
+	if len(merged.ReleaseDates) != 13 {
+		t.Fatalf("Expected 13 release dates, got %d", len(merged.ReleaseDates))
+	}
+	if !merged.OldestChartDate.Equal(day(1)) || !merged.NewestChartDate.Equal(day(19)) {
+		t.Errorf("Expected dates from day 1 to day 19, got %v to %v", merged.OldestChartDate, merged.NewestChartDate)
+	}
+	// Averaging the medians of both repositories would give day 8
+	if !merged.MedianChartDate.Equal(day(13)) {
+		t.Errorf("Expected the median of all dates, day 13, got %v", merged.MedianChartDate)
+	}
+	if got := merged.Quantile(0.1); !got.Equal(day(2)) {
+		t.Errorf("Expected p10 on day 2, got %v", got)
+	}
+	if got := merged.Quantile(0.9); !got.Equal(day(18)) {
+		t.Errorf("Expected p90 on day 18, got %v", got)
+	}
+
+	// The result does not depend on the order of the analyses
+	reversed := MergeAnalyses(small, large)
+	for i := range merged.ChartsInfo {
+		a, b := merged.ChartsInfo[i], reversed.ChartsInfo[i]
+		if a.Name != b.Name || a.Repository != b.Repository {
+			t.Errorf("Chart %d: %s/%s and %s/%s", i, a.Repository, a.Name, b.Repository, b.Name)
+		}
+	}
+	if first := merged.ChartsInfo[0]; first.Name != "app" || first.Repository != "large" {
+		t.Errorf("Expected charts sorted by name and repository, got %s/%s first", first.Repository, first.Name)
+	}
+	if !reversed.MedianChartDate.Equal(merged.MedianChartDate) {
+		t.Errorf("Expected the same median in any order")
+	}
+}
+
+func TestDateQuantile(t *testing.T) {
+	if got := DateQuantile(nil, 0.5); !got.IsZero() {
+		t.Errorf("Expected the zero time without dates, got %v", got)
+	}
+	dates := []time.Time{time.Unix(1, 0), time.Unix(2, 0)}
+	for q, expected := range map[float64]int64{0: 1, 0.5: 2, 1: 2} {
+		if got := DateQuantile(dates, q); got.Unix() != expected {
+			t.Errorf("Quantile %v: expected %d, got %d", q, expected, got.Unix())
+		}
+	}
+}
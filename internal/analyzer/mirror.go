@@ -0,0 +1,72 @@
+package analyzer
+
+import "time"
+
+// MirrorDrift describes how far a mirrored chart is behind its upstream
+type MirrorDrift struct {
+	Chart           string
+	MissingVersions []string
+	// Absent is set when the mirror carries none of the chart's upstream versions, either
+	// because it lacks the chart or only has versions that upstream does not
+	Absent bool
+	// Lag is the time between the newest upstream version and the newest upstream
+	// version present in the mirror. It is zero for absent charts.
+	Lag time.Duration
+}
+
+// CompareMirror reports, for every upstream chart and version that passes the mirror's
+// chart filter, which versions are missing from the mirror and how far behind it is.
+// Upstream creation dates are used for both sides, since mirrors commonly re-index
+// charts and rewrite their timestamps. Charts that exist only in the mirror are ignored.
+func CompareMirror(mirror, upstream *ChartAnalysis, filter *ChartFilter) []MirrorDrift {
+	mirrored := make(map[string]map[string]bool, len(mirror.ChartsInfo))
+	for _, chart := range mirror.ChartsInfo {
+		versions := make(map[string]bool, len(chart.Versions))
+		for _, v := range chart.Versions {
+			versions[v] = true
+		}
+		mirrored[chart.Name] = versions
+	}
+
+	drift := make([]MirrorDrift, 0, len(upstream.ChartsInfo))
+	for _, chart := range upstream.ChartsInfo {
+		d := MirrorDrift{Chart: chart.Name, MissingVersions: []string{}}
+		mirrorVersions := mirrored[chart.Name]
+
+		kept, present := 0, 0
+		var newestMirrored, newestUpstream time.Time
+		for _, detail := range chart.VersionDetails {
+			if !filter.KeepsVersion(chart.Name, detail.Version) {
+				continue
+			}
+			kept++
+			if detail.Created.After(newestUpstream) {
+				newestUpstream = detail.Created
+			}
+			if !mirrorVersions[detail.Version] {
+				d.MissingVersions = append(d.MissingVersions, detail.Version)
+				continue
+			}
+			present++
+			if detail.Created.After(newestMirrored) {
+				newestMirrored = detail.Created
+			}
+		}
+
+		if kept == 0 {
+			// The mirror's filter drops the whole chart
+			continue
+		}
+
+		switch {
+		case present == 0:
+			d.Absent = true
+		case newestUpstream.After(newestMirrored):
+			d.Lag = newestUpstream.Sub(newestMirrored)
+		}
+
+		drift = append(drift, d)
+	}
+
+	return drift
+}
@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareMirror(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC)
+	}
+	upstream := &ChartAnalysis{
+		ChartsInfo: []ChartInfo{
+			{
+				Name:           "in-sync",
+				Versions:       []string{"1.0.0"},
+				VersionDetails: []VersionDetail{{Version: "1.0.0", Created: day(1)}},
+				OldestVersion:  day(1),
+				NewestVersion:  day(1),
+			},
+			{
+				Name:     "behind",
+				Versions: []string{"1.2.0", "1.1.0", "1.0.0"},
+				VersionDetails: []VersionDetail{
+					{Version: "1.2.0", Created: day(10)},
+					{Version: "1.1.0", Created: day(5)},
+					{Version: "1.0.0", Created: day(2)},
+				},
+				OldestVersion: day(2),
+				NewestVersion: day(10),
+			},
+			{
+				Name:           "absent",
+				Versions:       []string{"0.1.0"},
+				VersionDetails: []VersionDetail{{Version: "0.1.0", Created: day(21)}},
+				OldestVersion:  day(21),
+				NewestVersion:  day(21),
+			},
+			{
+				Name:           "foreign-versions",
+				Versions:       []string{"2.0.0"},
+				VersionDetails: []VersionDetail{{Version: "2.0.0", Created: day(15)}},
+			},
+			{
+				Name:           "excluded",
+				Versions:       []string{"1.0.0"},
+				VersionDetails: []VersionDetail{{Version: "1.0.0", Created: day(3)}},
+			},
+			{
+				Name:     "prerelease",
+				Versions: []string{"2.0.0-rc.1", "1.0.0"},
+				VersionDetails: []VersionDetail{
+					{Version: "2.0.0-rc.1", Created: day(20)},
+					{Version: "1.0.0", Created: day(4)},
+				},
+			},
+		},
+	}
+	mirror := &ChartAnalysis{
+		ChartsInfo: []ChartInfo{
+			{Name: "in-sync", Versions: []string{"1.0.0"}},
+			{Name: "behind", Versions: []string{"1.0.0", "1.1.0"}},
+			{Name: "mirror-only", Versions: []string{"9.9.9"}},
+			{Name: "foreign-versions", Versions: []string{"1.0.0-custom"}},
+			{Name: "prerelease", Versions: []string{"1.0.0"}},
+		},
+	}
+
+	// The mirror only carries stable releases and skips excluded charts
+	filter, err := NewChartFilter(nil, []string{"excluded"}, ">= 0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	drift := CompareMirror(mirror, upstream, filter)
+	if len(drift) != 5 {
+		t.Fatalf("Expected drift for 5 upstream charts, got %d", len(drift))
+	}
+
+	byChart := make(map[string]MirrorDrift)
+	for _, d := range drift {
+		byChart[d.Chart] = d
+	}
+
+	if d := byChart["in-sync"]; len(d.MissingVersions) != 0 || d.Lag != 0 || d.Absent {
+		t.Errorf("Expected in-sync chart to have no drift, got %+v", d)
+	}
+	if d := byChart["behind"]; len(d.MissingVersions) != 1 || d.MissingVersions[0] != "1.2.0" || d.Lag != 5*24*time.Hour {
+		t.Errorf("Expected behind chart to miss 1.2.0 with 5 days lag, got %+v", d)
+	}
+	if d := byChart["absent"]; len(d.MissingVersions) != 1 || !d.Absent || d.Lag != 0 {
+		t.Errorf("Expected absent chart to be reported as absent without lag, got %+v", d)
+	}
+	if d := byChart["foreign-versions"]; len(d.MissingVersions) != 1 || !d.Absent || d.Lag != 0 {
+		t.Errorf("Expected a chart with only non-upstream versions to be reported as absent without lag, got %+v", d)
+	}
+	if d := byChart["prerelease"]; len(d.MissingVersions) != 0 || d.Lag != 0 {
+		t.Errorf("Expected versions outside the mirror's constraint to be ignored, got %+v", d)
+	}
+	if _, ok := byChart["excluded"]; ok {
+		t.Error("Charts excluded by the mirror's filter should not be reported")
+	}
+	if _, ok := byChart["mirror-only"]; ok {
+		t.Error("Charts that exist only in the mirror should not be reported")
+	}
+}
@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// IndexSource is a repository index that takes part in a merged index
+type IndexSource struct {
+	RepoURL string
+	Entries map[string][]ChartVersionInfo
+}
+
+// IndexFilter selects the charts and versions of a merged index
+type IndexFilter struct {
+	ExcludeDeprecated bool     // Drop charts whose latest version is deprecated
+	LatestVersions    int      // Keep only the newest N versions of each chart, 0 keeps all
+	Charts            []string // Only include these charts, empty includes all
+}
+
+// MergeIndexes combines repository indexes into one that `helm repo add` can use.
+// Sources are merged in order: when several define the same chart version, the first wins.
+// Relative chart URLs are resolved against their repository, so the merged index works
+// wherever it is served from. Credentials are removed from chart URLs, and URLs that
+// clients cannot download, such as file:// ones, are dropped along with versions left
+// without any URL.
+func MergeIndexes(sources []IndexSource, filter IndexFilter) *HelmIndex {
+	allowed := make(map[string]bool, len(filter.Charts))
+	for _, name := range filter.Charts {
+		allowed[name] = true
+	}
+
+	merged := make(map[string][]ChartVersionInfo)
+	seen := make(map[string]map[string]bool)
+	for _, source := range sources {
+		for name, versions := range source.Entries {
+			if len(allowed) > 0 && !allowed[name] {
+				continue
+			}
+			if seen[name] == nil {
+				seen[name] = make(map[string]bool)
+			}
+			for _, version := range versions {
+				if seen[name][version.Version] {
+					continue
+				}
+
+				var urls []string
+				for _, u := range version.URLs {
+					if resolved, ok := downloadURL(resolveChartURL(u, source.RepoURL)); ok {
+						urls = append(urls, resolved)
+					}
+				}
+				if len(urls) == 0 {
+					continue
+				}
+				seen[name][version.Version] = true
+				version.URLs = urls
+				merged[name] = append(merged[name], version)
+			}
+		}
+	}
+
+	for name, versions := range merged {
+		SortVersionsNewestFirst(versions)
+		if filter.ExcludeDeprecated && versions[0].Deprecated {
+			delete(merged, name)
+			continue
+		}
+		if filter.LatestVersions > 0 && len(versions) > filter.LatestVersions {
+			merged[name] = versions[:filter.LatestVersions]
+		}
+	}
+
+	return &HelmIndex{
+		APIVersion: "v1",
+		Entries:    merged,
+		Generated:  time.Now().UTC(),
+	}
+}
+
+// downloadURL returns an http(s) chart URL without its credentials, or false for
+// URLs that clients of a merged index could not download
+func downloadURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	u.User = nil
+	return u.String(), true
+}
+
+// SortVersionsNewestFirst orders versions by descending semantic version, as Helm does.
+// Versions that are not valid semver sort after the valid ones.
+func SortVersionsNewestFirst(versions []ChartVersionInfo) {
+	parsed := make(map[string]*semver.Version, len(versions))
+	for _, v := range versions {
+		if sv, err := semver.NewVersion(v.Version); err == nil {
+			parsed[v.Version] = sv
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, vj := parsed[versions[i].Version], parsed[versions[j].Version]
+		switch {
+		case vi != nil && vj != nil:
+			return vi.GreaterThan(vj)
+		case vi != nil || vj != nil:
+			return vi != nil
+		default:
+			return versions[i].Version > versions[j].Version
+		}
+	})
+}
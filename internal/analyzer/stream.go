@@ -0,0 +1,197 @@
+package analyzer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WalkIndex decodes an index.yaml from r and calls fn once per entry, in document order.
+//
+// Only JSON documents are memory-bounded: they are streamed entry by entry with
+// encoding/json and never hold more than one chart. YAML is parsed with the yaml.v3 node
+// API, so any valid layout is accepted: flow style, any indentation, anchors and aliases,
+// comments and multi-line scalars. yaml.v3 has no streaming parser, so the node tree of the
+// whole document is built first and peak memory grows with the document; entries are then
+// decoded one chart at a time and each chart's nodes are released once decoded.
+//
+// The returned HelmIndex carries apiVersion and generated; its Entries map is left empty.
+func WalkIndex(r io.Reader, fn func(chartName string, versions []ChartVersionInfo) error) (*HelmIndex, error) {
+	br := bufio.NewReader(r)
+	if startsWithJSON(br) {
+		return walkJSONIndex(br, fn)
+	}
+
+	var doc yaml.Node
+	if err := yaml.NewDecoder(br).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &HelmIndex{}, nil
+		}
+		return nil, fmt.Errorf("failed to parse index.yaml: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return &HelmIndex{}, nil
+	}
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("failed to parse index.yaml: not a mapping")
+	}
+
+	// Everything but the entries is decoded as the header
+	header := &yaml.Node{Kind: yaml.MappingNode, Tag: root.Tag}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "entries" {
+			header.Content = append(header.Content, root.Content[i], root.Content[i+1])
+			continue
+		}
+		if err := walkYAMLEntries(root.Content[i+1], fn); err != nil {
+			return nil, err
+		}
+		root.Content[i+1] = nil
+	}
+
+	index := &HelmIndex{}
+	if err := header.Decode(index); err != nil {
+		return nil, fmt.Errorf("failed to parse index.yaml: %w", err)
+	}
+	return index, nil
+}
+
+// walkYAMLEntries decodes the entries mapping one chart at a time, calling fn per chart
+func walkYAMLEntries(entries *yaml.Node, fn func(chartName string, versions []ChartVersionInfo) error) error {
+	entries = resolveAlias(entries)
+	switch {
+	case entries.Kind == yaml.ScalarNode && entries.Tag == "!!null":
+		return nil
+	case entries.Kind != yaml.MappingNode:
+		return errors.New("failed to parse index.yaml: entries is not a mapping")
+	}
+
+	for i := 0; i+1 < len(entries.Content); i += 2 {
+		chartName := entries.Content[i].Value
+
+		var versions []ChartVersionInfo
+		if err := entries.Content[i+1].Decode(&versions); err != nil {
+			return fmt.Errorf("failed to parse index.yaml entry %q: %w", chartName, err)
+		}
+		// Release the chart's nodes before handing its versions over
+		entries.Content[i+1] = nil
+		if err := fn(chartName, versions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAlias returns the node an alias points to, or the node itself
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// startsWithJSON reports whether the first non-whitespace byte of br opens a JSON object.
+// Only the buffered data is inspected, nothing is consumed.
+func startsWithJSON(br *bufio.Reader) bool {
+	for n := 1; n <= br.Size(); n++ {
+		peeked, err := br.Peek(n)
+		if len(peeked) < n {
+			return false
+		}
+		switch peeked[n-1] {
+		case ' ', '\t', '\r', '\n':
+			if err != nil {
+				return false
+			}
+		default:
+			return peeked[n-1] == '{'
+		}
+	}
+	return false
+}
+
+// walkJSONIndex streams a JSON index, decoding one entry at a time
+func walkJSONIndex(r io.Reader, fn func(chartName string, versions []ChartVersionInfo) error) (*HelmIndex, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	index := &HelmIndex{}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON index: %w", err)
+		}
+
+		switch key {
+		case "apiVersion":
+			err = dec.Decode(&index.APIVersion)
+		case "generated":
+			err = dec.Decode(&index.Generated)
+		case "entries":
+			err = walkJSONEntries(dec, fn)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON index: %w", err)
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// walkJSONEntries decodes the entries object of a JSON index, calling fn per chart
+func walkJSONEntries(dec *json.Decoder, fn func(chartName string, versions []ChartVersionInfo) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return errors.New("entries is not an object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		chartName, _ := tok.(string)
+
+		var versions []ChartVersionInfo
+		if err := dec.Decode(&versions); err != nil {
+			return fmt.Errorf("entry %q: %w", chartName, err)
+		}
+		if err := fn(chartName, versions); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// expectDelim consumes the next JSON token and checks it is the given delimiter
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse JSON index: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("failed to parse JSON index: expected %q, got %v", want, tok)
+	}
+	return nil
+}
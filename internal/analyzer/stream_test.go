@@ -0,0 +1,337 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// syntheticIndex builds an index.yaml with charts*versions entries in the layout Helm writes
+func syntheticIndex(charts, versions int) []byte {
+	var buf bytes.Buffer
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	buf.WriteString("apiVersion: v1\nentries:\n")
+	for c := 0; c < charts; c++ {
+		fmt.Fprintf(&buf, "  chart-%d:\n", c)
+		for v := versions - 1; v >= 0; v-- {
+			created := base.Add(time.Duration(c*versions+v) * time.Hour)
+			fmt.Fprintf(&buf, "  - apiVersion: v2\n")
+			fmt.Fprintf(&buf, "    name: chart-%d\n", c)
+			fmt.Fprintf(&buf, "    version: 1.%d.0\n", v)
+			fmt.Fprintf(&buf, "    appVersion: 2.%d.0\n", v)
+			fmt.Fprintf(&buf, "    description: Synthetic chart %d for benchmarking the index parser\n", c)
+			fmt.Fprintf(&buf, "    created: %s\n", created.Format(time.RFC3339Nano))
+			fmt.Fprintf(&buf, "    digest: %064x\n", c*versions+v)
+			fmt.Fprintf(&buf, "    urls:\n    - charts/chart-%d-1.%d.0.tgz\n", c, v)
+		}
+	}
+	fmt.Fprintf(&buf, "generated: %s\n", base.Format(time.RFC3339Nano))
+
+	return buf.Bytes()
+}
+
+func TestWalkIndex_MatchesParseIndex(t *testing.T) {
+	data := syntheticIndex(20, 5)
+
+	index, err := ParseIndex(data)
+	if err != nil {
+		t.Fatalf("ParseIndex failed: %v", err)
+	}
+	expected := AnalyzeChartsWithRepo(index, "repo", "https://charts.example.com/index.yaml")
+
+	builder := NewAnalysisBuilder("repo", "https://charts.example.com/index.yaml")
+	header, err := WalkIndex(bytes.NewReader(data), func(chartName string, versions []ChartVersionInfo) error {
+		builder.AddChart(chartName, versions)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkIndex failed: %v", err)
+	}
+	actual := builder.Analysis()
+
+	if header.APIVersion != index.APIVersion || !header.Generated.Equal(index.Generated) {
+		t.Errorf("Header mismatch: got %q/%v, expected %q/%v", header.APIVersion, header.Generated, index.APIVersion, index.Generated)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Error("Streaming analysis differs from ParseIndex analysis")
+	}
+}
+
+func TestParseIndex_JSON(t *testing.T) {
+	yamlIndex, err := ParseIndex(syntheticIndex(5, 3))
+	if err != nil {
+		t.Fatalf("ParseIndex failed: %v", err)
+	}
+	data, err := json.Marshal(yamlIndex)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+
+	jsonIndex, err := ParseIndex(data)
+	if err != nil {
+		t.Fatalf("ParseIndex of JSON failed: %v", err)
+	}
+	if !reflect.DeepEqual(yamlIndex, jsonIndex) {
+		t.Error("JSON index differs from YAML index")
+	}
+
+	// The streaming JSON path yields the same entries in document order
+	walked := make(map[string][]ChartVersionInfo)
+	header, err := WalkIndex(bytes.NewReader(data), func(chartName string, versions []ChartVersionInfo) error {
+		walked[chartName] = versions
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkIndex of JSON failed: %v", err)
+	}
+	if header.APIVersion != "v1" || !header.Generated.Equal(yamlIndex.Generated) {
+		t.Errorf("Unexpected header %q/%v", header.APIVersion, header.Generated)
+	}
+	if !reflect.DeepEqual(walked, yamlIndex.Entries) {
+		t.Error("Walked JSON entries differ from YAML entries")
+	}
+}
+
+func TestParseIndex_JSONFallback(t *testing.T) {
+	// Numeric versions are not valid for encoding/json but YAML accepts them
+	index, err := ParseIndex([]byte(`{"apiVersion": "v1", "entries": {"app": [{"name": "app", "version": 1.0}]}}`))
+	if err != nil {
+		t.Fatalf("ParseIndex failed: %v", err)
+	}
+	if got := index.Entries["app"][0].Version; got != "1.0" {
+		t.Errorf("Expected version 1.0, got %q", got)
+	}
+}
+
+func TestWalkIndex_Layouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]int
+	}{
+		{
+			name: "helm layout with comments and block scalars",
+			data: `# generated by CI
+apiVersion: v1
+entries:
+  # first chart
+  alpha:
+  - name: alpha
+    version: 1.0.0
+    description: |
+      Multi-line description
+
+      with a blank line
+  - name: alpha
+    version: 0.9.0
+
+  beta:
+  - name: beta
+    version: 2.0.0
+generated: "2024-01-01T00:00:00Z"
+`,
+			expected: map[string]int{"alpha": 2, "beta": 1},
+		},
+		{
+			name: "indented sequences",
+			data: `apiVersion: v1
+entries:
+    gamma:
+        - name: gamma
+          version: 1.0.0
+        - name: gamma
+          version: 1.1.0
+`,
+			expected: map[string]int{"gamma": 2},
+		},
+		{
+			name:     "flow style entries",
+			data:     "apiVersion: v1\nentries: {delta: [{name: delta, version: 1.0.0}]}\n",
+			expected: map[string]int{"delta": 1},
+		},
+		{
+			name:     "json",
+			data:     `{"apiVersion": "v1", "entries": {"epsilon": [{"name": "epsilon", "version": "1.0.0"}]}}`,
+			expected: map[string]int{"epsilon": 1},
+		},
+		{
+			name:     "empty entries",
+			data:     "apiVersion: v1\nentries: {}\n",
+			expected: map[string]int{},
+		},
+		{
+			name: "one-space indentation and nested flow sequences",
+			data: `entries:
+ zeta:
+  - {name: zeta, version: 1.0.0, urls: [zeta-1.0.0.tgz]}
+  - name: zeta
+    version: 0.1.0
+ eta: [{name: eta, version: 3.0.0}]
+apiVersion: v1
+`,
+			expected: map[string]int{"zeta": 2, "eta": 1},
+		},
+		{
+			name: "anchors and aliases across charts",
+			data: `apiVersion: v1
+entries:
+  theta:
+  - &base
+    name: theta
+    version: 1.0.0
+    maintainers: &team
+    - name: Platform
+  - <<: *base
+    version: 1.1.0
+  iota:
+  - name: iota
+    version: 1.0.0
+    maintainers: *team
+  lambda: &shared
+  - name: lambda
+    version: 1.0.0
+  kappa: *shared
+`,
+			expected: map[string]int{"theta": 2, "iota": 1, "lambda": 1, "kappa": 1},
+		},
+		{
+			name: "multi-line strings and comments at column 0",
+			data: `apiVersion: v1
+entries:
+  mu:
+  - name: mu
+    version: 1.0.0
+    description: "a quoted description
+continued: at column 0"
+# a comment at column 0 inside the entries
+    home: https://mu.example.com
+  nu:
+  - name: nu
+    version: 1.0.0
+generated: "2024-01-01T00:00:00Z"
+`,
+			expected: map[string]int{"mu": 1, "nu": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walked := make(map[string][]ChartVersionInfo)
+			actual := make(map[string]int)
+			header, err := WalkIndex(bytes.NewReader([]byte(tt.data)), func(chartName string, versions []ChartVersionInfo) error {
+				walked[chartName] = versions
+				actual[chartName] = len(versions)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkIndex failed: %v", err)
+			}
+			if header.APIVersion != "v1" {
+				t.Errorf("Expected apiVersion v1, got %q", header.APIVersion)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected entries %v, got %v", tt.expected, actual)
+			}
+
+			// Every layout decodes to the same versions as the whole-document parser
+			index, err := ParseIndex([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("ParseIndex failed: %v", err)
+			}
+			if len(index.Entries) > 0 && !reflect.DeepEqual(walked, index.Entries) {
+				t.Errorf("Walked entries differ from ParseIndex: %v vs %v", walked, index.Entries)
+			}
+		})
+	}
+}
+
+func TestWalkIndex_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"top level is a list", "- a\n- b\n"},
+		{"entries is a list", "entries:\n- a\n"},
+		{"invalid version entry", "entries:\n  app:\n    name: app\n"},
+		{"malformed yaml", "entries: [\n"},
+		{"alias before its anchor", "entries:\n  app: *versions\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := WalkIndex(bytes.NewReader([]byte(tt.data)), func(string, []ChartVersionInfo) error { return nil })
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// The benchmarks use a 100k-version index (1000 charts x 100 versions); run with
+// `go test -bench . -benchmem ./internal/analyzer`. For YAML, both paths build the node
+// tree of the whole document and allocate about the same, so WalkIndex does not bound
+// their peak memory. The JSON benchmark streams the same index one chart at a time.
+
+func BenchmarkParseIndexAndAnalyze(b *testing.B) {
+	data := syntheticIndex(1000, 100)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		index, err := ParseIndex(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		AnalyzeChartsWithRepo(index, "bench", "https://charts.example.com/index.yaml")
+	}
+}
+
+func BenchmarkWalkIndexAndAnalyze(b *testing.B) {
+	data := syntheticIndex(1000, 100)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		builder := NewAnalysisBuilder("bench", "https://charts.example.com/index.yaml")
+		_, err := WalkIndex(bytes.NewReader(data), func(chartName string, versions []ChartVersionInfo) error {
+			builder.AddChart(chartName, versions)
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		builder.Analysis()
+	}
+}
+
+func BenchmarkWalkJSONIndexAndAnalyze(b *testing.B) {
+	index, err := ParseIndex(syntheticIndex(1000, 100))
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		builder := NewAnalysisBuilder("bench", "https://charts.example.com/index.json")
+		_, err := WalkIndex(bytes.NewReader(data), func(chartName string, versions []ChartVersionInfo) error {
+			builder.AddChart(chartName, versions)
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		builder.Analysis()
+	}
+}
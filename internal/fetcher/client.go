@@ -1,19 +1,36 @@
 package fetcher
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/obezpalko/helm-repo-exporter/pkg/config"
 )
 
+// ErrNotFound is returned when the requested file does not exist on the server
+var ErrNotFound = errors.New("not found")
+
+// ErrIndexTooLarge is returned when an index exceeds the repository's maximum size
+var ErrIndexTooLarge = errors.New("index exceeds maximum size")
+
 // Client wraps the HTTP client for fetching index.yaml
 type Client struct {
 	httpClient *http.Client
 	repo       config.Repository
+	onFetch    func(bytes int64) // Called with the size of every completed fetch, may be nil
 }
 
 // NewClient creates a new HTTP client for fetching index.yaml
@@ -26,34 +43,395 @@ func NewClient(repo config.Repository, timeout time.Duration) *Client {
 	}
 }
 
+// AllowHosts rejects redirects to hosts that allowed refuses, so that credentials
+// are not forwarded to other servers
+func (c *Client) AllowHosts(allowed func(host string) bool) {
+	c.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !allowed(req.URL.Hostname()) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+// OnFetch sets a callback that receives the number of bytes of every completed fetch:
+// the transferred size of an index once it is closed, and the size of every file
+func (c *Client) OnFetch(fn func(bytes int64)) {
+	c.onFetch = fn
+}
+
 // GetIndexYAML retrieves and returns the index.yaml file from the URL
 func (c *Client) GetIndexYAML(ctx context.Context) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.repo.URL, nil)
+	body, err := c.OpenIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return data, nil
+}
+
+// OpenIndex starts downloading the index file and returns its body for streaming.
+// gzip and zstd transfer encodings are negotiated with the server, and gzip-compressed
+// files such as index.yaml.gz are decompressed, so the reader always yields the plain index.
+// file:// repositories are read from disk; a directory URL reads its index.yaml.
+// Reads fail with ErrIndexTooLarge once the decoded index exceeds the repository's MaxIndexSize.
+// The caller must close the returned reader.
+func (c *Client) OpenIndex(ctx context.Context) (*IndexReader, error) {
+	src, err := c.openIndexSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := c.repo.MaxIndexSize
+	if limit > 0 && src.size > limit {
+		src.body.Close()
+		return nil, fmt.Errorf("%w: %s is %d bytes, limit is %d", ErrIndexTooLarge, c.repo.URL, src.size, limit)
+	}
+
+	index := &IndexReader{closers: []io.Closer{src.body}, lastModified: src.lastModified, onClose: c.onFetch}
+	index.transferred = &countingReader{r: src.body}
+
+	body, err := decodeContent(index.transferred, src.encoding)
+	if err == nil {
+		index.addCloser(body)
+		if strings.HasSuffix(strings.ToLower(src.path), ".gz") {
+			body, err = gunzipIfCompressed(body)
+			index.addCloser(body)
+		}
+	}
+	if err != nil {
+		index.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %w", c.repo.URL, err)
+	}
+
+	if limit > 0 {
+		body = &limitedReader{r: body, remaining: limit, limit: limit}
+	}
+	index.decoded = &countingReader{r: body}
+
+	return index, nil
+}
+
+// indexSource is a raw, possibly compressed, index body with its metadata
+type indexSource struct {
+	body         io.ReadCloser
+	size         int64 // -1 when unknown
+	encoding     string
+	path         string
+	lastModified time.Time
+}
+
+func (c *Client) openIndexSource(ctx context.Context) (*indexSource, error) {
+	if path, ok := LocalPath(c.repo.URL); ok {
+		return openLocalIndex(path)
+	}
+
+	resp, err := c.get(ctx, c.repo.URL, "zstd, gzip")
+	if err != nil {
+		return nil, err
+	}
+
+	src := &indexSource{
+		body:     resp.Body,
+		size:     resp.ContentLength,
+		encoding: resp.Header.Get("Content-Encoding"),
+		path:     resp.Request.URL.Path,
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		src.lastModified = lastModified
+	}
+	return src, nil
+}
+
+// openLocalIndex opens an index file, or the index.yaml inside a directory
+func openLocalIndex(path string) (*indexSource, error) {
+	path, err := LocalIndexPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path) // #nosec G304 -- path comes from operator configuration
+	if err != nil {
+		return nil, localError(path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, localError(path, err)
+	}
+
+	return &indexSource{
+		body:         f,
+		size:         info.Size(),
+		path:         path,
+		lastModified: info.ModTime(),
+	}, nil
+}
+
+// LocalPath returns the filesystem path of a file:// URL
+func LocalPath(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), true
+}
+
+// LocalIndexPath returns path itself, or path/index.yaml when path is a directory
+func LocalIndexPath(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", localError(path, err)
+	}
+	if info.IsDir() {
+		return filepath.Join(path, "index.yaml"), nil
+	}
+	return path, nil
+}
+
+func localError(path string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	return fmt.Errorf("failed to read %s: %w", path, err)
+}
+
+// GetFile retrieves an arbitrary file, such as a chart tarball or provenance file.
+// file:// URLs are read from disk, but only for repositories that are themselves local.
+// Authentication is only sent when the file is served by the repository's own host,
+// so credentials never leak to third-party chart mirrors.
+// Returns ErrNotFound (wrapped) when the server responds with 404.
+func (c *Client) GetFile(ctx context.Context, fileURL string) ([]byte, error) {
+	if path, ok := LocalPath(fileURL); ok {
+		// A remote index must not be able to make the exporter read local files
+		if _, local := LocalPath(c.repo.URL); !local {
+			return nil, fmt.Errorf("refusing to read %s for remote repository %s", fileURL, c.repo.Name)
+		}
+		data, err := os.ReadFile(path) // #nosec G304 -- only reachable for repositories configured as file://
+		if err != nil {
+			return nil, localError(path, err)
+		}
+		c.fetched(len(data))
+		return data, nil
+	}
+
+	resp, err := c.get(ctx, fileURL, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	c.fetched(len(data))
+
+	return data, nil
+}
+
+// FileSize returns the size of a file, such as a chart tarball, without downloading it:
+// from the filesystem for local repositories, or from the Content-Length of a HEAD request.
+// Authentication follows the same rules as GetFile.
+func (c *Client) FileSize(ctx context.Context, fileURL string) (int64, error) {
+	if path, ok := LocalPath(fileURL); ok {
+		if _, local := LocalPath(c.repo.URL); !local {
+			return 0, fmt.Errorf("refusing to read %s for remote repository %s", fileURL, c.repo.Name)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, localError(path, err)
+		}
+		return info.Size(), nil
+	}
+
+	resp, err := c.do(ctx, http.MethodHead, fileURL, "")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("%s has no Content-Length", fileURL)
+	}
+	return resp.ContentLength, nil
+}
+
+func (c *Client) fetched(n int) {
+	if c.onFetch != nil {
+		c.onFetch(int64(n))
+	}
+}
+
+// get performs an authenticated GET request and checks the response status.
+// A non-empty acceptEncoding disables Go's transparent gzip handling; the caller then
+// has to decode the body according to its Content-Encoding.
+// On success the caller owns the response body.
+func (c *Client) get(ctx context.Context, fileURL, acceptEncoding string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, fileURL, acceptEncoding)
+}
+
+// do performs an authenticated request and checks the response status
+func (c *Client) do(ctx context.Context, method, fileURL, acceptEncoding string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fileURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
 
 	// Add authentication if configured
-	if c.repo.Auth != nil {
+	if c.repo.Auth != nil && c.sameHost(req.URL) {
 		c.addAuthentication(req)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch %s: %w", c.repo.URL, err)
+		return nil, fmt.Errorf("failed to fetch %s: %w", fileURL, err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, c.repo.URL)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s: %w", fileURL, ErrNotFound)
+		}
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, fileURL)
 	}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	return resp, nil
+}
+
+// IndexReader is a streaming index download. It counts the bytes received from the
+// server and the bytes of decoded index, which differ when the index is compressed.
+type IndexReader struct {
+	transferred  *countingReader
+	decoded      *countingReader
+	closers      []io.Closer
+	lastModified time.Time
+	onClose      func(transferred int64)
+}
+
+// Read reads decoded index data
+func (i *IndexReader) Read(p []byte) (int, error) {
+	return i.decoded.Read(p)
+}
+
+// Close releases the decompressors and the response body
+func (i *IndexReader) Close() error {
+	if i.onClose != nil {
+		i.onClose(i.transferred.n)
+		i.onClose = nil
 	}
 
-	return data, nil
+	var errs []error
+	for j := len(i.closers) - 1; j >= 0; j-- {
+		errs = append(errs, i.closers[j].Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (i *IndexReader) addCloser(r io.Reader) {
+	if closer, ok := r.(io.Closer); ok {
+		i.closers = append(i.closers, closer)
+	}
+}
+
+// TransferredBytes returns the number of bytes received from the server so far
+func (i *IndexReader) TransferredBytes() int64 {
+	return i.transferred.n
+}
+
+// DecodedBytes returns the number of decoded index bytes read so far
+func (i *IndexReader) DecodedBytes() int64 {
+	return i.decoded.n
+}
+
+// LastModified returns the server's Last-Modified time, or the zero time if it sent none
+func (i *IndexReader) LastModified() time.Time {
+	return i.lastModified
+}
+
+// decodeContent wraps r with a decoder for the response's Content-Encoding
+func decodeContent(r io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// gunzipIfCompressed decompresses r if it starts with the gzip magic number. Servers
+// disagree on whether a .gz file is sent as a gzip Content-Encoding or as opaque data,
+// so the body is sniffed rather than trusting the headers.
+func gunzipIfCompressed(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// limitedReader fails reads once more than limit bytes have been read
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// Read at most one byte past the limit, which is enough to detect an oversized body
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), fmt.Errorf("%w of %d bytes", ErrIndexTooLarge, l.limit)
+	}
+	return n, err
+}
+
+// sameHost reports whether u points at the same host as the repository URL
+func (c *Client) sameHost(u *url.URL) bool {
+	repoURL, err := url.Parse(c.repo.URL)
+	if err != nil {
+		return false
+	}
+	return repoURL.Host == u.Host
 }
 
 // addAuthentication adds authentication headers to the request
@@ -0,0 +1,308 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+)
+
+const testIndex = "apiVersion: v1\nentries: {}\n"
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, data string) []byte {
+	t.Helper()
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll([]byte(data), nil)
+}
+
+func TestOpenIndex_Decompression(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		encoding string
+		body     func(t *testing.T) []byte
+	}{
+		{name: "plain", path: "/index.yaml", body: func(_ *testing.T) []byte { return []byte(testIndex) }},
+		{name: "gzip encoding", path: "/index.yaml", encoding: "gzip", body: func(t *testing.T) []byte { return gzipped(t, testIndex) }},
+		{name: "zstd encoding", path: "/index.yaml", encoding: "zstd", body: func(t *testing.T) []byte { return zstded(t, testIndex) }},
+		{name: "gz file", path: "/index.yaml.gz", body: func(t *testing.T) []byte { return gzipped(t, testIndex) }},
+		{name: "gz file already decoded", path: "/index.yaml.gz", body: func(_ *testing.T) []byte { return []byte(testIndex) }},
+		{name: "gz file with gzip encoding", path: "/index.yaml.gz", encoding: "gzip", body: func(t *testing.T) []byte { return gzipped(t, string(gzipped(t, testIndex))) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); !strings.Contains(got, "zstd") || !strings.Contains(got, "gzip") {
+					t.Errorf("Expected zstd and gzip to be accepted, got %q", got)
+				}
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			client := NewClient(config.Repository{Name: "test", URL: server.URL + tt.path}, 5*time.Second)
+			index, err := client.OpenIndex(context.Background())
+			if err != nil {
+				t.Fatalf("OpenIndex failed: %v", err)
+			}
+			defer index.Close()
+
+			data, err := io.ReadAll(index)
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if string(data) != testIndex {
+				t.Errorf("Expected %q, got %q", testIndex, data)
+			}
+			if index.TransferredBytes() != int64(len(body)) {
+				t.Errorf("Expected %d bytes transferred, got %d", len(body), index.TransferredBytes())
+			}
+			if index.DecodedBytes() != int64(len(testIndex)) {
+				t.Errorf("Expected %d bytes decoded, got %d", len(testIndex), index.DecodedBytes())
+			}
+		})
+	}
+}
+
+func TestOpenIndex_LimitAppliesToDecodedSize(t *testing.T) {
+	large := strings.Repeat("#", 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(gzipped(t, large))
+	}))
+	defer server.Close()
+
+	client := NewClient(config.Repository{Name: "test", URL: server.URL + "/index.yaml", MaxIndexSize: 1 << 16}, 5*time.Second)
+	index, err := client.OpenIndex(context.Background())
+	if err != nil {
+		t.Fatalf("OpenIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	if _, err := io.ReadAll(index); !errors.Is(err, ErrIndexTooLarge) {
+		t.Errorf("Expected ErrIndexTooLarge, got %v", err)
+	}
+}
+
+func TestOpenIndex_LastModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		_, _ = w.Write([]byte(testIndex))
+	}))
+	defer server.Close()
+
+	client := NewClient(config.Repository{Name: "test", URL: server.URL + "/index.yaml"}, 5*time.Second)
+	index, err := client.OpenIndex(context.Background())
+	if err != nil {
+		t.Fatalf("OpenIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	if !index.LastModified().Equal(modified) {
+		t.Errorf("Expected Last-Modified %v, got %v", modified, index.LastModified())
+	}
+}
+
+func TestOnFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testIndex))
+	}))
+	defer server.Close()
+
+	var fetched int64
+	client := NewClient(config.Repository{Name: "test", URL: server.URL + "/index.yaml"}, 5*time.Second)
+	client.OnFetch(func(bytes int64) { fetched += bytes })
+
+	if _, err := client.GetIndexYAML(context.Background()); err != nil {
+		t.Fatalf("GetIndexYAML failed: %v", err)
+	}
+	if _, err := client.GetFile(context.Background(), server.URL+"/app-1.0.0.tgz.prov"); err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	if expected := int64(2 * len(testIndex)); fetched != expected {
+		t.Errorf("Expected %d fetched bytes, got %d", expected, fetched)
+	}
+}
+
+func TestSetTLSConfig_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testIndex))
+	}))
+	defer server.Close()
+
+	repo := config.Repository{Name: "test", URL: server.URL + "/index.yaml"}
+	if _, err := NewClient(repo, 5*time.Second).GetIndexYAML(context.Background()); err == nil {
+		t.Fatal("Expected the self-signed certificate to be rejected without a CA file")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig, err := NewTLSConfig(&config.TLSConfig{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewTLSConfig failed: %v", err)
+	}
+
+	client := NewClient(repo, 5*time.Second)
+	client.SetTLSConfig(tlsConfig)
+	if _, err := client.GetIndexYAML(context.Background()); err != nil {
+		t.Errorf("Expected the certificate to be trusted with the CA file, got %v", err)
+	}
+}
+
+func TestOpenIndex_LocalFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.yaml"), []byte(testIndex), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, repoURL := range []string{"file://" + dir, "file://" + filepath.Join(dir, "index.yaml")} {
+		client := NewClient(config.Repository{Name: "local", URL: repoURL}, 5*time.Second)
+		data, err := client.GetIndexYAML(context.Background())
+		if err != nil {
+			t.Fatalf("%s: GetIndexYAML failed: %v", repoURL, err)
+		}
+		if string(data) != testIndex {
+			t.Errorf("%s: expected %q, got %q", repoURL, testIndex, data)
+		}
+	}
+
+	client := NewClient(config.Repository{Name: "local", URL: "file://" + filepath.Join(dir, "missing")}, 5*time.Second)
+	if _, err := client.GetIndexYAML(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetFile_LocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	chart := filepath.Join(dir, "app-1.0.0.tgz")
+	if err := os.WriteFile(chart, []byte("tarball"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	local := NewClient(config.Repository{Name: "local", URL: "file://" + dir}, 5*time.Second)
+	data, err := local.GetFile(context.Background(), "file://"+chart)
+	if err != nil || string(data) != "tarball" {
+		t.Errorf("Expected tarball, got %q (%v)", data, err)
+	}
+	if _, err := local.GetFile(context.Background(), "file://"+chart+".prov"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	remote := NewClient(config.Repository{Name: "remote", URL: "https://charts.example.com/index.yaml"}, 5*time.Second)
+	if _, err := remote.GetFile(context.Background(), "file://"+chart); err == nil {
+		t.Error("Expected remote repository to be refused local files")
+	}
+}
+
+func TestFileSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected a HEAD request, got %s", r.Method)
+		}
+		if _, pass, _ := r.BasicAuth(); pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Length", "2048")
+	}))
+	defer server.Close()
+
+	client := NewClient(config.Repository{
+		Name: "remote", URL: server.URL + "/index.yaml",
+		Auth: &config.AuthConfig{Basic: &config.BasicAuth{Username: "user", Password: "secret"}},
+	}, 5*time.Second)
+	if size, err := client.FileSize(context.Background(), server.URL+"/app-1.0.0.tgz"); err != nil || size != 2048 {
+		t.Errorf("Expected 2048 bytes, got %d (%v)", size, err)
+	}
+
+	dir := t.TempDir()
+	chart := filepath.Join(dir, "app-1.0.0.tgz")
+	if err := os.WriteFile(chart, []byte("tarball"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	local := NewClient(config.Repository{Name: "local", URL: "file://" + dir}, 5*time.Second)
+	if size, err := local.FileSize(context.Background(), "file://"+chart); err != nil || size != 7 {
+		t.Errorf("Expected 7 bytes, got %d (%v)", size, err)
+	}
+	if _, err := client.FileSize(context.Background(), "file://"+chart); err == nil {
+		t.Error("Expected remote repository to be refused local files")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "index.yaml")
+	if err := os.WriteFile(indexPath, []byte(testIndex), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 10)
+	client := NewClient(config.Repository{Name: "local", URL: "file://" + dir, Watch: true}, 5*time.Second)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Watch(ctx, func() { changed <- struct{}{} })
+	}()
+
+	// Replace the index the way generators do: write a temporary file and rename it
+	time.Sleep(100 * time.Millisecond)
+	tmp := filepath.Join(dir, "index.yaml.tmp")
+	if err := os.WriteFile(tmp, []byte(testIndex+"# changed\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, indexPath); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case err := <-done:
+		t.Fatalf("Watch returned early: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("No change notification")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long Watch waits for a burst of filesystem events to settle
+const watchDebounce = 500 * time.Millisecond
+
+// Watch calls onChange whenever the index of a file:// repository changes on disk.
+// Bursts of events, such as an index being written in several chunks, result in a
+// single call. Watch blocks until ctx is done or the watcher fails.
+func (c *Client) Watch(ctx context.Context, onChange func()) error {
+	path, ok := LocalPath(c.repo.URL)
+	if !ok {
+		return fmt.Errorf("repository %s is not a file:// repository", c.repo.Name)
+	}
+	indexPath, err := LocalIndexPath(path)
+	if err != nil {
+		return err
+	}
+	indexPath = filepath.Clean(indexPath)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the directory rather than the file: index generators usually replace the
+	// file with a rename, which would silently drop a watch on the file itself
+	if err := watcher.Add(filepath.Dir(indexPath)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", indexPath, err)
+	}
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == indexPath && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("watching %s: %w", indexPath, err)
+		case <-debounce.C:
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirSource lists chart tarballs in a local directory tree
+type DirSource struct {
+	root string
+}
+
+// NewDirSource creates a source for the directory at root. If root is a file, such as
+// the repository's index.yaml, its directory is used.
+func NewDirSource(root string) (*DirSource, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chart directory: %w", err)
+	}
+	if !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+	return &DirSource{root: root}, nil
+}
+
+// List walks the directory tree for .tgz files
+func (d *DirSource) List(_ context.Context) ([]ChartFile, error) {
+	var files []ChartFile
+	err := filepath.WalkDir(d.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tgz") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, ChartFile{Path: filepath.ToSlash(rel), ModTime: info.ModTime().UTC()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", d.root, err)
+	}
+	return files, nil
+}
+
+// Open opens a tarball returned by List
+func (d *DirSource) Open(_ context.Context, path string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(d.root, filepath.FromSlash(path))) // #nosec G304 -- path was returned by List
+}
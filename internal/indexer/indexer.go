@@ -0,0 +1,218 @@
+// Package indexer builds Helm repository indexes from chart tarballs, for repositories
+// whose published index.yaml is missing or broken.
+package indexer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/fetcher"
+	"gopkg.in/yaml.v3"
+)
+
+// maxChartYAMLSize bounds how much of a Chart.yaml is read from a tarball
+const maxChartYAMLSize = 1 << 20
+
+// ChartFile is a chart tarball found in a source
+type ChartFile struct {
+	Path    string // Slash-separated path relative to the source root, used as the chart URL
+	ModTime time.Time
+	ETag    string // Set by sources that report one, such as S3
+}
+
+// Source lists and opens chart tarballs
+type Source interface {
+	List(ctx context.Context) ([]ChartFile, error)
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+}
+
+// NewSource creates a source for a file:// or s3://bucket/prefix URL.
+// s3 configures S3 sources and is ignored for local ones.
+func NewSource(rawURL string, s3 S3Options, httpClient *http.Client) (Source, error) {
+	if root, ok := fetcher.LocalPath(rawURL); ok {
+		return NewDirSource(root)
+	}
+	switch {
+	case strings.HasPrefix(rawURL, "s3://"):
+		return NewS3Source(rawURL, s3, httpClient)
+	default:
+		return nil, fmt.Errorf("unsupported chart source %q (expected file:// or s3://)", rawURL)
+	}
+}
+
+// Generate reads every chart tarball of src and builds an index from their Chart.yaml files,
+// as `helm repo index` would. Chart URLs are relative to the source root and versions are
+// ordered newest first. Unreadable tarballs are skipped: the index of the readable ones is
+// returned together with an error listing the others.
+func Generate(ctx context.Context, src Source) (*analyzer.HelmIndex, error) {
+	return NewGenerator(src).Generate(ctx)
+}
+
+// Generator generates indexes of a source repeatedly, reading only the tarballs that were
+// added or changed since the previous run
+type Generator struct {
+	src Source
+
+	mu    sync.Mutex
+	cache map[ChartFile]analyzer.ChartVersionInfo // Keyed by path, modification time and ETag
+}
+
+// NewGenerator creates a generator for src
+func NewGenerator(src Source) *Generator {
+	return &Generator{src: src, cache: make(map[ChartFile]analyzer.ChartVersionInfo)}
+}
+
+// Generate builds an index like the Generate function, reusing the charts read by earlier
+// runs for tarballs whose path, modification time and ETag are unchanged
+func (g *Generator) Generate(ctx context.Context) (*analyzer.HelmIndex, error) {
+	files, err := g.src.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var versions []analyzer.ChartVersionInfo
+	cache := make(map[ChartFile]analyzer.ChartVersionInfo, len(files))
+	var changed []ChartFile
+	for _, file := range files {
+		if version, ok := g.cache[file]; ok {
+			cache[file] = version
+			versions = append(versions, version)
+		} else {
+			changed = append(changed, file)
+		}
+	}
+
+	const workers = 8
+	jobs := make(chan ChartFile)
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				version, err := readChartFile(ctx, g.src, file)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", file.Path, err))
+				} else {
+					cache[file] = *version
+					versions = append(versions, *version)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, file := range changed {
+		jobs <- file
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Tarballs that were removed, changed or unreadable are dropped from the cache
+	g.cache = cache
+
+	index := &analyzer.HelmIndex{
+		APIVersion: "v1",
+		Entries:    make(map[string][]analyzer.ChartVersionInfo),
+		Generated:  time.Now().UTC(),
+	}
+	for _, version := range versions {
+		// Callers may rewrite the URLs of the index, so it does not share them with the cache
+		version.URLs = append([]string(nil), version.URLs...)
+		index.Entries[version.Name] = append(index.Entries[version.Name], version)
+	}
+	for _, entry := range index.Entries {
+		analyzer.SortVersionsNewestFirst(entry)
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return index, errors.Join(errs...)
+}
+
+func readChartFile(ctx context.Context, src Source, file ChartFile) (*analyzer.ChartVersionInfo, error) {
+	body, err := src.Open(ctx, file.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	version, err := ReadChart(body)
+	if err != nil {
+		return nil, err
+	}
+	version.URLs = []string{file.Path}
+	version.Created = file.ModTime
+	return version, nil
+}
+
+// ReadChart reads the Chart.yaml of a chart tarball and computes the tarball's digest.
+// The returned version has no URLs or created timestamp.
+func ReadChart(r io.Reader) (*analyzer.ChartVersionInfo, error) {
+	hash := sha256.New()
+	raw := io.TeeReader(r, hash)
+
+	gz, err := gzip.NewReader(raw)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip file: %w", err)
+	}
+	defer gz.Close()
+
+	var version *analyzer.ChartVersionInfo
+	tr := tar.NewReader(gz)
+	for version == nil {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("no Chart.yaml found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tarball: %w", err)
+		}
+
+		// Chart.yaml sits in the chart's top-level directory, e.g. mychart/Chart.yaml
+		dir, name := path.Split(path.Clean(header.Name))
+		if name != "Chart.yaml" || strings.Count(dir, "/") != 1 {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, maxChartYAMLSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Chart.yaml: %w", err)
+		}
+		version = &analyzer.ChartVersionInfo{}
+		if err := yaml.Unmarshal(data, version); err != nil {
+			return nil, fmt.Errorf("failed to parse Chart.yaml: %w", err)
+		}
+	}
+
+	if version.Name == "" || version.Version == "" {
+		return nil, errors.New("missing name or version in Chart.yaml")
+	}
+
+	// Hash the rest of the file; the digest covers the whole tarball
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return nil, fmt.Errorf("failed to read tarball: %w", err)
+	}
+	version.Digest = hex.EncodeToString(hash.Sum(nil))
+
+	return version, nil
+}
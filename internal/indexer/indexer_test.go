@@ -0,0 +1,248 @@
+package indexer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+// chartTarball builds a minimal chart tarball containing only Chart.yaml
+func chartTarball(t *testing.T, name, version string) []byte {
+	t.Helper()
+	chartYAML := fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\ndescription: Test chart\nkeywords: [test]\n", name, version)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := map[string]string{
+		name + "/Chart.yaml":                chartYAML,
+		name + "/charts/sub/Chart.yaml":     "name: sub\nversion: 9.9.9\n",
+		name + "/templates/deployment.yaml": "kind: Deployment\n",
+	}
+	for _, path := range []string{name + "/templates/deployment.yaml", name + "/charts/sub/Chart.yaml", name + "/Chart.yaml"} {
+		content := files[path]
+		if err := tw.WriteHeader(&tar.Header{Name: path, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestGenerate_DirSource(t *testing.T) {
+	dir := t.TempDir()
+	tarballs := map[string][]byte{
+		"app-1.0.0.tgz":        chartTarball(t, "app", "1.0.0"),
+		"app-1.10.0.tgz":       chartTarball(t, "app", "1.10.0"),
+		"nested/app-1.2.0.tgz": chartTarball(t, "app", "1.2.0"),
+		"db-0.1.0.tgz":         chartTarball(t, "db", "0.1.0"),
+		"broken-1.0.0.tgz":     []byte("not a tarball"),
+	}
+	for path, data := range tarballs {
+		full := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.yaml"), []byte("broken: ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file path resolves to its directory, so the repository URL itself can be the source
+	src, err := NewSource("file://"+filepath.Join(dir, "index.yaml"), S3Options{}, nil)
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	index, err := Generate(context.Background(), src)
+	if err == nil {
+		t.Error("Expected an error for the broken tarball")
+	}
+	if index == nil {
+		t.Fatal("Expected an index despite the broken tarball")
+	}
+
+	app := index.Entries["app"]
+	var versions []string
+	for _, v := range app {
+		versions = append(versions, v.Version)
+	}
+	if fmt.Sprint(versions) != "[1.10.0 1.2.0 1.0.0]" {
+		t.Errorf("Expected versions newest first, got %v", versions)
+	}
+	if app[1].URLs[0] != "nested/app-1.2.0.tgz" {
+		t.Errorf("Expected relative URL, got %v", app[1].URLs)
+	}
+	if app[0].Digest != digest(tarballs["app-1.10.0.tgz"]) {
+		t.Errorf("Digest mismatch for app 1.10.0")
+	}
+	if app[0].Created.IsZero() || len(app[0].Keywords) != 1 || app[0].APIVersion != "v2" {
+		t.Errorf("Chart metadata not populated: %+v", app[0])
+	}
+	if len(index.Entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(index.Entries))
+	}
+
+	// The written index is readable by the regular parser
+	var buf bytes.Buffer
+	if err := analyzer.WriteIndex(&buf, index); err != nil {
+		t.Fatalf("WriteIndex failed: %v", err)
+	}
+	parsed, err := analyzer.ParseIndex(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseIndex failed: %v", err)
+	}
+	if len(parsed.Entries["app"]) != 3 || parsed.Entries["app"][0].Digest != app[0].Digest {
+		t.Error("Written index does not round-trip")
+	}
+}
+
+func TestGenerate_S3Source(t *testing.T) {
+	chart := chartTarball(t, "app", "1.0.0")
+	modified := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bucket/", func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(auth, "/eu-west-1/s3/aws4_request") || r.Header.Get("X-Amz-Security-Token") != "token" {
+			http.Error(w, "unsigned request", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/bucket/charts/app-1.0.0.tgz" {
+			_, _ = w.Write(chart)
+			return
+		}
+		if r.URL.Path != "/bucket/" || r.URL.Query().Get("prefix") != "charts/" {
+			http.NotFound(w, r)
+			return
+		}
+		// Two pages, the first one truncated
+		if r.URL.Query().Get("continuation-token") == "" {
+			fmt.Fprint(w, `<ListBucketResult><IsTruncated>true</IsTruncated><NextContinuationToken>page2</NextContinuationToken>
+<Contents><Key>charts/index.yaml</Key><LastModified>2024-03-01T10:00:00.000Z</LastModified></Contents></ListBucketResult>`)
+			return
+		}
+		fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>
+<Contents><Key>charts/app-1.0.0.tgz</Key><LastModified>2024-03-01T10:00:00.000Z</LastModified><ETag>"abc"</ETag></Contents></ListBucketResult>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	src, err := NewSource("s3://bucket/charts", S3Options{
+		Endpoint:    server.URL,
+		Region:      "eu-west-1",
+		Credentials: &S3Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"},
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("NewSource failed: %v", err)
+	}
+	index, err := Generate(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	versions := index.Entries["app"]
+	if len(versions) != 1 {
+		t.Fatalf("Expected one version, got %d", len(versions))
+	}
+	if versions[0].URLs[0] != "app-1.0.0.tgz" || !versions[0].Created.Equal(modified) || versions[0].Digest != digest(chart) {
+		t.Errorf("Unexpected version %+v", versions[0])
+	}
+}
+
+// countingSource counts the tarballs opened from a source
+type countingSource struct {
+	Source
+	opened []string
+}
+
+func (c *countingSource) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	c.opened = append(c.opened, path)
+	return c.Source.Open(ctx, path)
+}
+
+func TestGenerator_ReadsChangedChartsOnly(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte, modified time.Time) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	write("app-1.0.0.tgz", chartTarball(t, "app", "1.0.0"), day(1))
+	write("db-0.1.0.tgz", chartTarball(t, "db", "0.1.0"), day(1))
+
+	dirSource, err := NewDirSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &countingSource{Source: dirSource}
+	gen := NewGenerator(src)
+
+	index, err := gen.Generate(context.Background())
+	if err != nil || len(index.Entries) != 2 {
+		t.Fatalf("First run: %v, %v", index, err)
+	}
+	index.Entries["app"][0].URLs[0] = "https://charts.example.com/app-1.0.0.tgz"
+
+	// A changed tarball is read again, a new one is read, a removed one is dropped
+	write("app-1.0.0.tgz", chartTarball(t, "app", "1.0.0"), day(2))
+	write("web-2.0.0.tgz", chartTarball(t, "web", "2.0.0"), day(2))
+	if err := os.Remove(filepath.Join(dir, "db-0.1.0.tgz")); err != nil {
+		t.Fatal(err)
+	}
+	src.opened = nil
+	index, err = gen.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Second run failed: %v", err)
+	}
+	if fmt.Sprint(src.opened) != "[app-1.0.0.tgz web-2.0.0.tgz]" && fmt.Sprint(src.opened) != "[web-2.0.0.tgz app-1.0.0.tgz]" {
+		t.Errorf("Expected the changed and new tarballs to be read, got %v", src.opened)
+	}
+	if len(index.Entries) != 2 || index.Entries["db"] != nil || !index.Entries["app"][0].Created.Equal(day(2)) {
+		t.Errorf("Unexpected entries %v", index.Entries)
+	}
+
+	// Unchanged tarballs are not read, and rewritten URLs do not leak into the cache
+	src.opened = nil
+	index, err = gen.Generate(context.Background())
+	if err != nil || len(src.opened) != 0 {
+		t.Fatalf("Expected no tarballs to be read, got %v (%v)", src.opened, err)
+	}
+	if url := index.Entries["app"][0].URLs[0]; url != "app-1.0.0.tgz" {
+		t.Errorf("Expected the relative URL, got %q", url)
+	}
+}
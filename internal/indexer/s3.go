@@ -0,0 +1,166 @@
+package indexer
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultS3Region is used when neither the options nor the environment set a region
+const defaultS3Region = "us-east-1"
+
+// S3Options configure how an S3 source reaches its bucket
+type S3Options struct {
+	// Endpoint overrides the AWS endpoint, e.g. for S3-compatible storage
+	Endpoint string
+
+	// Region signs requests and selects the AWS endpoint, defaulting to AWS_REGION,
+	// AWS_DEFAULT_REGION or us-east-1
+	Region string
+
+	// Credentials sign requests; nil looks them up with DefaultS3Credentials, and requests
+	// are anonymous when none are found
+	Credentials *S3Credentials
+}
+
+// S3Source lists chart tarballs under an S3 prefix using the ListObjectsV2 API.
+// Requests are signed with Signature Version 4 when credentials are available.
+type S3Source struct {
+	baseURL     string // Bucket URL ending in "/"
+	prefix      string // Key prefix ending in "/", or empty
+	region      string
+	credentials *S3Credentials // nil for anonymous requests
+	httpClient  *http.Client
+}
+
+// NewS3Source creates a source for an s3://bucket/prefix URL. Without an endpoint the
+// bucket is addressed virtual-host style on AWS; with one it is addressed path-style.
+func NewS3Source(rawURL string, opts S3Options, httpClient *http.Client) (*S3Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 URL %q (expected s3://bucket/prefix)", rawURL)
+	}
+
+	src := &S3Source{
+		prefix:      strings.Trim(u.Path, "/"),
+		region:      opts.Region,
+		credentials: opts.Credentials,
+		httpClient:  httpClient,
+	}
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if src.region == "" {
+			src.region = os.Getenv(env)
+		}
+	}
+	if src.region == "" {
+		src.region = defaultS3Region
+	}
+	if src.credentials == nil {
+		if src.credentials, err = DefaultS3Credentials(); err != nil {
+			return nil, err
+		}
+	}
+
+	src.baseURL = "https://" + u.Host + ".s3." + src.region + ".amazonaws.com/"
+	if opts.Endpoint != "" {
+		src.baseURL = strings.TrimSuffix(opts.Endpoint, "/") + "/" + u.Host + "/"
+	}
+	if src.prefix != "" {
+		src.prefix += "/"
+	}
+	return src, nil
+}
+
+// listBucketResult is the subset of the ListObjectsV2 response that is used
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+	} `xml:"Contents"`
+}
+
+// List pages through the objects under the prefix and returns the .tgz ones
+func (s *S3Source) List(ctx context.Context) ([]ChartFile, error) {
+	var files []ChartFile
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		var result listBucketResult
+		if err := s.getXML(ctx, s.baseURL+"?"+query.Encode(), &result); err != nil {
+			return nil, err
+		}
+		for _, object := range result.Contents {
+			if strings.HasSuffix(object.Key, ".tgz") {
+				files = append(files, ChartFile{
+					Path:    strings.TrimPrefix(object.Key, s.prefix),
+					ModTime: object.LastModified,
+					ETag:    object.ETag,
+				})
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return files, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Open downloads a tarball returned by List
+func (s *S3Source) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	segments := strings.Split(s.prefix+path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	resp, err := s.get(ctx, s.baseURL+strings.Join(segments, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Source) getXML(ctx context.Context, listURL string, v any) error {
+	resp, err := s.get(ctx, listURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse bucket listing: %w", err)
+	}
+	return nil
+}
+
+func (s *S3Source) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.credentials != nil {
+		s.credentials.sign(req, s.region, time.Now())
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, rawURL)
+	}
+	return resp, nil
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OnScrapeCollector refreshes stale data before collecting the metrics registered with it,
+// so that Prometheus scrapes drive the exporter's fetches.
+// It implements prometheus.Registerer to be passed to NewMetrics, and prometheus.Collector
+// to be registered with the registry that is served.
+type OnScrapeCollector struct {
+	refresh func(ctx context.Context)
+	timeout time.Duration
+
+	mu         sync.Mutex
+	collectors []prometheus.Collector
+}
+
+// NewOnScrapeCollector creates a collector that calls refresh on every scrape and waits
+// for it at most timeout before collecting
+func NewOnScrapeCollector(refresh func(ctx context.Context), timeout time.Duration) *OnScrapeCollector {
+	return &OnScrapeCollector{refresh: refresh, timeout: timeout}
+}
+
+// Register adds a collector that is collected after every refresh
+func (c *OnScrapeCollector) Register(collector prometheus.Collector) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.collectors = append(c.collectors, collector)
+	return nil
+}
+
+// MustRegister adds collectors that are collected after every refresh
+func (c *OnScrapeCollector) MustRegister(collectors ...prometheus.Collector) {
+	for _, collector := range collectors {
+		_ = c.Register(collector)
+	}
+}
+
+// Unregister removes a collector
+func (c *OnScrapeCollector) Unregister(collector prometheus.Collector) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, registered := range c.collectors {
+		if registered == collector {
+			c.collectors = append(c.collectors[:i], c.collectors[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Describe implements prometheus.Collector
+func (c *OnScrapeCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.registered() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect refreshes stale data, then collects the registered metrics.
+// Data that is not refreshed within the timeout is collected as it was.
+func (c *OnScrapeCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	c.refresh(ctx)
+	cancel()
+
+	for _, collector := range c.registered() {
+		collector.Collect(ch)
+	}
+}
+
+// Cached returns a collector of the same metrics that never refreshes, for consumers such
+// as the OTLP exporter that must report the current values without driving fetches
+func (c *OnScrapeCollector) Cached() prometheus.Collector {
+	return cachedCollector{c}
+}
+
+type cachedCollector struct {
+	c *OnScrapeCollector
+}
+
+func (cc cachedCollector) Describe(ch chan<- *prometheus.Desc) {
+	cc.c.Describe(ch)
+}
+
+func (cc cachedCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range cc.c.registered() {
+		collector.Collect(ch)
+	}
+}
+
+func (c *OnScrapeCollector) registered() []prometheus.Collector {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]prometheus.Collector(nil), c.collectors...)
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestOnScrapeCollector_RefreshesBeforeCollecting(t *testing.T) {
+	var m *Metrics
+	refreshes := 0
+	collector := NewOnScrapeCollector(func(context.Context) {
+		refreshes++
+		m.ChartsTotal.WithLabelValues("test").Set(float64(refreshes))
+	}, time.Second)
+	m = NewMetrics(collector)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	for want := 1; want <= 2; want++ {
+		if _, err := registry.Gather(); err != nil {
+			t.Fatalf("Gather failed: %v", err)
+		}
+		if got := testutil.ToFloat64(m.ChartsTotal.WithLabelValues("test")); got != float64(want) {
+			t.Errorf("Scrape %d: expected refreshed value %d, got %v", want, want, got)
+		}
+	}
+}
+
+func TestOnScrapeCollector_Timeout(t *testing.T) {
+	collector := NewOnScrapeCollector(func(ctx context.Context) {
+		<-ctx.Done()
+	}, 50*time.Millisecond)
+	NewMetrics(collector)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	start := time.Now()
+	if _, err := registry.Gather(); err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the refresh to be bounded by the timeout, took %v", elapsed)
+	}
+}
+
+func TestOnScrapeCollector_CachedDoesNotRefresh(t *testing.T) {
+	refreshes := 0
+	collector := NewOnScrapeCollector(func(context.Context) { refreshes++ }, time.Second)
+	m := NewMetrics(collector)
+	m.ChartsTotal.WithLabelValues("test").Set(3)
+
+	// A second registry, as used for OTLP, reads the current values without refreshing
+	cached := prometheus.NewRegistry()
+	cached.MustRegister(collector.Cached())
+	if got := testutil.CollectAndCount(collector.Cached(), "helm_repo_charts_total"); got != 1 {
+		t.Errorf("Expected the cached collector to report the chart total, got %d series", got)
+	}
+	if _, err := cached.Gather(); err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	if refreshes != 0 {
+		t.Errorf("Expected no refresh from the cached collector, got %d", refreshes)
+	}
+
+	// The Prometheus registry still refreshes
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	if _, err := registry.Gather(); err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	if refreshes != 1 {
+		t.Errorf("Expected one refresh from the Prometheus registry, got %d", refreshes)
+	}
+}
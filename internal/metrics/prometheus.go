@@ -1,103 +1,303 @@
 package metrics
 
 import (
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// SeriesLimits caps the per-chart (helm_repo_chart_*) series exported by Update.
+// A limit of 0 means no limit.
+type SeriesLimits struct {
+	Global        int            // Across all repositories
+	PerRepository map[string]int // Per repository name
+	Aggregate     bool           // Drop every per-chart series of a repository over its limit instead of the excess
+}
+
 // Metrics holds all Prometheus metrics
 type Metrics struct {
-	ChartsTotal       *prometheus.GaugeVec
-	ChartVersions     *prometheus.GaugeVec
-	ChartAgeOldest    *prometheus.GaugeVec
-	ChartAgeNewest    *prometheus.GaugeVec
-	ChartAgeMedian    *prometheus.GaugeVec
-	OverallAgeOldest  *prometheus.GaugeVec
-	OverallAgeNewest  *prometheus.GaugeVec
-	OverallAgeMedian  *prometheus.GaugeVec
-	TotalVersions     *prometheus.GaugeVec
-	ScrapeDuration    *prometheus.HistogramVec
-	ScrapeErrors      *prometheus.CounterVec
-	LastScrapeSuccess *prometheus.GaugeVec
-}
-
-// NewMetrics creates and registers Prometheus metrics
-func NewMetrics() *Metrics {
+	ChartsTotal         *prometheus.GaugeVec
+	ChartVersions       *prometheus.GaugeVec
+	ChartAgeOldest      *prometheus.GaugeVec
+	ChartAgeNewest      *prometheus.GaugeVec
+	ChartAgeMedian      *prometheus.GaugeVec
+	OverallAgeOldest    *prometheus.GaugeVec
+	OverallAgeNewest    *prometheus.GaugeVec
+	OverallAgeMedian    *prometheus.GaugeVec
+	OverallAgeQuantile  *prometheus.GaugeVec
+	CombinedAgeQuantile *prometheus.GaugeVec
+	TotalVersions       *prometheus.GaugeVec
+	ScrapeDuration      *prometheus.HistogramVec
+	ScrapeErrors        *prometheus.CounterVec
+	LastScrapeSuccess   *prometheus.GaugeVec
+	ChartSignatures     *prometheus.GaugeVec
+	ChartDeprecated     *prometheus.GaugeVec
+	DeprecatedCharts    *prometheus.GaugeVec
+	MirrorMissing       *prometheus.GaugeVec
+	MirrorLag           *prometheus.GaugeVec
+	MirrorAbsent        *prometheus.GaugeVec
+	ChartDependencies   *prometheus.GaugeVec
+	LintViolations      *prometheus.GaugeVec
+	IndexTransferred    *prometheus.CounterVec
+	IndexDecoded        *prometheus.CounterVec
+	IndexSize           *prometheus.GaugeVec
+	IndexGenerated      *prometheus.GaugeVec
+	IndexAPIVersion     *prometheus.GaugeVec
+	IndexLastModified   *prometheus.GaugeVec
+	IndexRegenerated    *prometheus.GaugeVec
+	ChartsFiltered      *prometheus.GaugeVec
+	SeriesDropped       *prometheus.CounterVec
+	SeriesWithheld      *prometheus.GaugeVec
+
+	// Exporter self-metrics
+	BuildInfo       *prometheus.GaugeVec
+	HTTPDuration    *prometheus.HistogramVec
+	FetchedBytes    *prometheus.CounterVec
+	ScrapesInFlight prometheus.Gauge
+
+	mu                  sync.Mutex
+	limits              SeriesLimits
+	chartDemand         map[string]int             // Per-chart series each repository's last analysis asked for
+	chartSeries         map[string]int             // Per-chart series currently exported per repository
+	exportedCharts      map[string]map[string]bool // Charts with exported series per repository
+	droppedCharts       map[string]int             // Per-chart series withheld by Update per repository
+	droppedDependencies map[string]int             // Dependency series withheld by UpdateDependencies per repository
+}
+
+// NewMetrics creates Prometheus metrics and registers them with reg
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	factory := promauto.With(reg)
 	return &Metrics{
-		ChartsTotal: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		ChartsTotal: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_charts_total",
 			Help: "Total number of distinct Helm charts in the repository",
 		}, []string{"repository"}),
-		ChartVersions: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		ChartVersions: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_chart_versions",
 			Help: "Number of versions for each Helm chart",
 		}, []string{"repository", "chart"}),
-		ChartAgeOldest: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		ChartAgeOldest: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_chart_age_oldest_seconds",
 			Help: "Timestamp of the oldest version of each chart",
 		}, []string{"repository", "chart"}),
-		ChartAgeNewest: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		ChartAgeNewest: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_chart_age_newest_seconds",
 			Help: "Timestamp of the newest version of each chart",
 		}, []string{"repository", "chart"}),
-		ChartAgeMedian: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		ChartAgeMedian: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_chart_age_median_seconds",
 			Help: "Timestamp of the median version of each chart",
 		}, []string{"repository", "chart"}),
-		OverallAgeOldest: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		OverallAgeOldest: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_overall_age_oldest_seconds",
 			Help: "Timestamp of the oldest chart version in the repository",
 		}, []string{"repository"}),
-		OverallAgeNewest: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		OverallAgeNewest: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_overall_age_newest_seconds",
 			Help: "Timestamp of the newest chart version in the repository",
 		}, []string{"repository"}),
-		OverallAgeMedian: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		OverallAgeMedian: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_overall_age_median_seconds",
 			Help: "Timestamp of the median chart version in the repository",
 		}, []string{"repository"}),
-		TotalVersions: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		OverallAgeQuantile: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_overall_age_quantile_seconds",
+			Help: "Timestamp below which the given quantile of chart versions in the repository were created",
+		}, []string{"repository", "quantile"}),
+		CombinedAgeQuantile: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_combined_age_quantile_seconds",
+			Help: "Timestamp below which the given quantile of chart versions across all repositories were created",
+		}, []string{"quantile"}),
+		TotalVersions: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_versions_total",
 			Help: "Total number of chart versions in the repository",
 		}, []string{"repository"}),
-		ScrapeDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		ScrapeDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "helm_repo_scrape_duration_seconds",
 			Help:    "Duration of the repository scrape operation in seconds",
 			Buckets: prometheus.DefBuckets,
 		}, []string{"repository"}),
-		ScrapeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+		ScrapeErrors: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "helm_repo_scrape_errors_total",
 			Help: "Total number of scrape errors per repository",
 		}, []string{"repository"}),
-		LastScrapeSuccess: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		LastScrapeSuccess: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "helm_repo_last_scrape_success",
 			Help: "Timestamp of the last successful scrape per repository",
 		}, []string{"repository"}),
+		ChartSignatures: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_chart_signatures",
+			Help: "Number of chart versions by provenance signature status (signed, unsigned, invalid)",
+		}, []string{"repository", "chart", "status"}),
+		ChartDeprecated: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_chart_deprecated",
+			Help: "Whether the latest version of each chart is marked as deprecated (1) or not (0)",
+		}, []string{"repository", "chart"}),
+		DeprecatedCharts: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_deprecated_charts_total",
+			Help: "Number of deprecated Helm charts in the repository",
+		}, []string{"repository"}),
+		MirrorMissing: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_mirror_missing_versions",
+			Help: "Number of upstream chart versions missing from the mirror repository",
+		}, []string{"repository", "upstream", "chart"}),
+		MirrorLag: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_mirror_lag_seconds",
+			Help: "Seconds between the newest upstream chart version and the newest version present in the mirror",
+		}, []string{"repository", "upstream", "chart"}),
+		MirrorAbsent: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_mirror_chart_absent",
+			Help: "Whether the mirror repository carries none of the versions of an upstream chart (1) or some (0)",
+		}, []string{"repository", "upstream", "chart"}),
+		ChartDependencies: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_chart_dependencies",
+			Help: "Number of dependencies of the latest chart version by resolution status (resolved, outdated, unresolvable, external, local)",
+		}, []string{"repository", "chart", "status"}),
+		LintViolations: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_lint_violations",
+			Help: "Number of index lint violations per rule and severity",
+		}, []string{"repository", "rule", "severity"}),
+		IndexTransferred: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "helm_repo_index_transferred_bytes_total",
+			Help: "Total bytes of index data received from the repository, before decompression",
+		}, []string{"repository"}),
+		IndexDecoded: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "helm_repo_index_decoded_bytes_total",
+			Help: "Total bytes of index data after decompression",
+		}, []string{"repository"}),
+		IndexSize: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_index_size_bytes",
+			Help: "Size of the repository index after decompression, as of the last successful scrape",
+		}, []string{"repository"}),
+		IndexGenerated: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_index_generated_timestamp_seconds",
+			Help: "Timestamp the index claims it was generated at (its generated field)",
+		}, []string{"repository"}),
+		IndexAPIVersion: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_index_api_version_info",
+			Help: "The apiVersion declared by the repository index, always 1",
+		}, []string{"repository", "api_version"}),
+		IndexLastModified: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_index_last_modified_timestamp_seconds",
+			Help: "Last-Modified time the server reported for the index",
+		}, []string{"repository"}),
+		IndexRegenerated: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_index_regenerated",
+			Help: "Whether the last analysis used an index generated from chart tarballs (1) instead of the published one (0)",
+		}, []string{"repository"}),
+		ChartsFiltered: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_charts_filtered",
+			Help: "Number of charts in the repository index dropped by the repository's chart filter",
+		}, []string{"repository"}),
+		SeriesDropped: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "helm_repo_metrics_series_dropped_total",
+			Help: "Total number of per-chart series withheld by metric updates because a series limit was exceeded",
+		}, []string{"repository"}),
+		SeriesWithheld: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_metrics_series_withheld",
+			Help: "Number of per-chart series currently withheld because a series limit was exceeded",
+		}, []string{"repository"}),
+		BuildInfo: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "helm_repo_exporter_build_info",
+			Help: "Build information of the exporter, always 1",
+		}, []string{"version", "commit", "goversion"}),
+		HTTPDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "helm_repo_exporter_http_request_duration_seconds",
+			Help:    "Duration of HTTP requests served by the exporter, per handler",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"handler", "code"}),
+		FetchedBytes: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "helm_repo_exporter_fetched_bytes_total",
+			Help: "Total bytes fetched per repository over the wire, including indexes and provenance files",
+		}, []string{"repository"}),
+		ScrapesInFlight: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "helm_repo_exporter_scrapes_in_flight",
+			Help: "Number of repository scrapes currently running",
+		}),
+		chartDemand:         make(map[string]int),
+		chartSeries:         make(map[string]int),
+		exportedCharts:      make(map[string]map[string]bool),
+		droppedCharts:       make(map[string]int),
+		droppedDependencies: make(map[string]int),
 	}
 }
 
-// Update updates all metrics based on the chart analysis for a specific repository
+// SetBuildInfo exports the exporter's version and commit
+func (m *Metrics) SetBuildInfo(version, commit string) {
+	m.BuildInfo.Reset()
+	m.BuildInfo.WithLabelValues(version, commit, runtime.Version()).Set(1)
+}
+
+// InstrumentHandler records the latency of h under the given handler name
+func (m *Metrics) InstrumentHandler(name string, h http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerDuration(m.HTTPDuration.MustCurryWith(prometheus.Labels{"handler": name}), h)
+}
+
+// SetSeriesLimits sets the per-chart series limits applied by subsequent updates
+func (m *Metrics) SetSeriesLimits(limits SeriesLimits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = limits
+}
+
+// Update updates all metrics based on the chart analysis for a specific repository.
+// Series are overwritten in place and only stale ones are deleted, so a concurrent
+// scrape never sees a repository without its per-chart series.
 func (m *Metrics) Update(repository string, analysis *analyzer.ChartAnalysis) {
 	// Update per-repository metrics
 	m.ChartsTotal.WithLabelValues(repository).Set(float64(analysis.TotalCharts))
+	m.ChartsFiltered.WithLabelValues(repository).Set(float64(analysis.FilteredCharts))
 	m.TotalVersions.WithLabelValues(repository).Set(float64(analysis.TotalVersions))
+	m.DeprecatedCharts.WithLabelValues(repository).Set(float64(analysis.DeprecatedCharts))
 
-	// Update per-chart metrics
-	for _, chart := range analysis.ChartsInfo {
+	// Update per-chart metrics, within the series limits
+	allowed, stale := m.allowedCharts(repository, analysis.ChartsInfo)
+	for _, chart := range allowed {
 		m.ChartVersions.WithLabelValues(repository, chart.Name).Set(float64(chart.VersionCount))
 
-		if !chart.OldestVersion.IsZero() {
-			m.ChartAgeOldest.WithLabelValues(repository, chart.Name).Set(float64(chart.OldestVersion.Unix()))
+		deprecated := 0.0
+		if chart.Deprecated {
+			deprecated = 1
 		}
-		if !chart.NewestVersion.IsZero() {
-			m.ChartAgeNewest.WithLabelValues(repository, chart.Name).Set(float64(chart.NewestVersion.Unix()))
+		m.ChartDeprecated.WithLabelValues(repository, chart.Name).Set(deprecated)
+
+		for _, age := range []struct {
+			vec *prometheus.GaugeVec
+			t   time.Time
+		}{
+			{m.ChartAgeOldest, chart.OldestVersion},
+			{m.ChartAgeNewest, chart.NewestVersion},
+			{m.ChartAgeMedian, chart.MedianVersion},
+		} {
+			if age.t.IsZero() {
+				age.vec.DeleteLabelValues(repository, chart.Name)
+			} else {
+				age.vec.WithLabelValues(repository, chart.Name).Set(float64(age.t.Unix()))
+			}
 		}
-		if !chart.MedianVersion.IsZero() {
-			m.ChartAgeMedian.WithLabelValues(repository, chart.Name).Set(float64(chart.MedianVersion.Unix()))
+
+		if chart.ProvenanceChecked() {
+			m.ChartSignatures.WithLabelValues(repository, chart.Name, string(analyzer.SignatureSigned)).Set(float64(chart.SignedVersions))
+			m.ChartSignatures.WithLabelValues(repository, chart.Name, string(analyzer.SignatureUnsigned)).Set(float64(chart.UnsignedVersions))
+			m.ChartSignatures.WithLabelValues(repository, chart.Name, string(analyzer.SignatureInvalid)).Set(float64(chart.InvalidVersions))
+		} else {
+			m.ChartSignatures.DeletePartialMatch(prometheus.Labels{"repository": repository, "chart": chart.Name})
 		}
 	}
 
+	// Drop series of charts that were removed from the index, filtered out or withheld since the last scrape
+	for _, chart := range stale {
+		m.deleteChartSeries(prometheus.Labels{"repository": repository, "chart": chart})
+	}
+
 	// Update overall age metrics for this repository
 	if !analysis.OldestChartDate.IsZero() {
 		m.OverallAgeOldest.WithLabelValues(repository).Set(float64(analysis.OldestChartDate.Unix()))
@@ -108,6 +308,263 @@ func (m *Metrics) Update(repository string, analysis *analyzer.ChartAnalysis) {
 	if !analysis.MedianChartDate.IsZero() {
 		m.OverallAgeMedian.WithLabelValues(repository).Set(float64(analysis.MedianChartDate.Unix()))
 	}
+	m.OverallAgeQuantile.DeletePartialMatch(prometheus.Labels{"repository": repository})
+	for _, q := range analyzer.ReleaseQuantiles {
+		if t := analysis.Quantile(q); !t.IsZero() {
+			m.OverallAgeQuantile.WithLabelValues(repository, quantileLabel(q)).Set(float64(t.Unix()))
+		}
+	}
+}
+
+// UpdateCombinedAges replaces the release date quantiles of all repositories combined,
+// computed from their merged, sorted release dates
+func (m *Metrics) UpdateCombinedAges(dates []time.Time) {
+	m.CombinedAgeQuantile.Reset()
+	for _, q := range analyzer.ReleaseQuantiles {
+		if t := analyzer.DateQuantile(dates, q); !t.IsZero() {
+			m.CombinedAgeQuantile.WithLabelValues(quantileLabel(q)).Set(float64(t.Unix()))
+		}
+	}
+}
+
+// quantileLabel formats a quantile the way Prometheus summaries do, such as "0.5"
+func quantileLabel(q float64) string {
+	return strconv.FormatFloat(q, 'g', -1, 64)
+}
+
+// chartSeriesCount returns the number of per-chart series Update exports for a chart
+func chartSeriesCount(chart analyzer.ChartInfo) int {
+	n := 2 // versions and deprecated
+	for _, t := range []time.Time{chart.OldestVersion, chart.NewestVersion, chart.MedianVersion} {
+		if !t.IsZero() {
+			n++
+		}
+	}
+	if chart.ProvenanceChecked() {
+		n += 3
+	}
+	return n
+}
+
+// allowedCharts applies the series limits to a repository's charts and records the result.
+// It also returns the previously exported charts that are no longer exported.
+// When truncating, the most recently updated charts are kept.
+func (m *Metrics) allowedCharts(repository string, charts []analyzer.ChartInfo) ([]analyzer.ChartInfo, []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := 0
+	for _, chart := range charts {
+		total += chartSeriesCount(chart)
+	}
+	m.chartDemand[repository] = total
+
+	limit := m.limits.PerRepository[repository]
+	if m.limits.Global > 0 {
+		// Never exceed the global limit while other repositories still export more than
+		// their share; they give series back on their next update
+		remaining := m.limits.Global
+		for repo, n := range m.chartSeries {
+			if repo != repository {
+				remaining -= n
+			}
+		}
+		budget := min(m.globalShare(repository), max(remaining, 0))
+		if limit == 0 || budget < limit {
+			limit = budget
+		}
+	}
+
+	allowed := charts
+	if (limit > 0 || m.limits.Global > 0) && total > limit {
+		allowed = nil
+		if !m.limits.Aggregate {
+			byRecency := append([]analyzer.ChartInfo(nil), charts...)
+			sort.SliceStable(byRecency, func(i, j int) bool {
+				return byRecency[i].NewestVersion.After(byRecency[j].NewestVersion)
+			})
+			used := 0
+			for _, chart := range byRecency {
+				if n := chartSeriesCount(chart); used+n <= limit {
+					allowed = append(allowed, chart)
+					used += n
+				}
+			}
+		}
+	}
+
+	exported := 0
+	names := make(map[string]bool, len(allowed))
+	for _, chart := range allowed {
+		exported += chartSeriesCount(chart)
+		names[chart.Name] = true
+	}
+	var stale []string
+	for name := range m.exportedCharts[repository] {
+		if !names[name] {
+			stale = append(stale, name)
+		}
+	}
+	m.chartSeries[repository] = exported
+	m.exportedCharts[repository] = names
+	m.droppedCharts[repository] = total - exported
+	if dropped := total - exported; dropped > 0 {
+		m.SeriesDropped.WithLabelValues(repository).Add(float64(dropped))
+	}
+	m.SeriesWithheld.WithLabelValues(repository).Set(float64(m.droppedCharts[repository] + m.droppedDependencies[repository]))
+
+	return allowed, stale
+}
+
+// globalShare returns the part of the global limit a repository may use. The limit is
+// handed out in repository name order, each repository taking the series its last analysis
+// asked for within its own limit, so the split does not depend on the order of scrapes.
+// The caller must hold m.mu.
+func (m *Metrics) globalShare(repository string) int {
+	repos := make([]string, 0, len(m.chartDemand))
+	for repo := range m.chartDemand {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	remaining := m.limits.Global
+	for _, repo := range repos {
+		want := m.chartDemand[repo]
+		if limit := m.limits.PerRepository[repo]; limit > 0 && want > limit {
+			want = limit
+			if m.limits.Aggregate {
+				// Aggregate mode exports nothing for a repository over its own limit
+				want = 0
+			}
+		}
+		share := min(want, remaining)
+		if repo == repository {
+			return share
+		}
+		if m.limits.Aggregate && share < want {
+			share = 0
+		}
+		remaining -= share
+	}
+	return remaining
+}
+
+// chartExported reports whether a chart's per-chart series are exported
+func (m *Metrics) chartExported(repository, chart string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names, ok := m.exportedCharts[repository]
+	return !ok || names[chart]
+}
+
+// deleteChartSeries removes the per-chart series matching labels
+func (m *Metrics) deleteChartSeries(labels prometheus.Labels) {
+	for _, vec := range []*prometheus.GaugeVec{
+		m.ChartVersions,
+		m.ChartAgeOldest,
+		m.ChartAgeNewest,
+		m.ChartAgeMedian,
+		m.ChartSignatures,
+		m.ChartDeprecated,
+	} {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
+// UpdateMirror replaces the mirror drift metrics of a mirror repository
+func (m *Metrics) UpdateMirror(repository, upstream string, drift []analyzer.MirrorDrift) {
+	// Drop series for charts that no longer exist upstream
+	m.MirrorMissing.DeletePartialMatch(prometheus.Labels{"repository": repository})
+	m.MirrorLag.DeletePartialMatch(prometheus.Labels{"repository": repository})
+	m.MirrorAbsent.DeletePartialMatch(prometheus.Labels{"repository": repository})
+
+	for _, d := range drift {
+		absent := 0.0
+		if d.Absent {
+			absent = 1
+		}
+		m.MirrorMissing.WithLabelValues(repository, upstream, d.Chart).Set(float64(len(d.MissingVersions)))
+		m.MirrorLag.WithLabelValues(repository, upstream, d.Chart).Set(d.Lag.Seconds())
+		m.MirrorAbsent.WithLabelValues(repository, upstream, d.Chart).Set(absent)
+	}
+}
+
+// UpdateDependencies replaces the dependency metrics with the given graph
+func (m *Metrics) UpdateDependencies(graph *analyzer.DependencyGraph) {
+	m.ChartDependencies.Reset()
+	dropped := make(map[string]int)
+	for key, counts := range graph.StatusCounts() {
+		if !m.chartExported(key[0], key[1]) {
+			dropped[key[0]] += len(counts)
+			continue
+		}
+		for status, count := range counts {
+			m.ChartDependencies.WithLabelValues(key[0], key[1], string(status)).Set(float64(count))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for repo := range m.droppedDependencies {
+		if dropped[repo] == 0 {
+			m.SeriesWithheld.WithLabelValues(repo).Set(float64(m.droppedCharts[repo]))
+		}
+	}
+	for repo, n := range dropped {
+		m.SeriesDropped.WithLabelValues(repo).Add(float64(n))
+		m.SeriesWithheld.WithLabelValues(repo).Set(float64(m.droppedCharts[repo] + n))
+	}
+	m.droppedDependencies = dropped
+}
+
+// UpdateLint replaces the lint violation metrics of a repository
+// Every active rule is exported, with zero when it found nothing, so alerts can rely on the series
+func (m *Metrics) UpdateLint(repository string, rules []analyzer.LintRule, violations []analyzer.LintViolation) {
+	m.LintViolations.DeletePartialMatch(prometheus.Labels{"repository": repository})
+
+	counts := make(map[string]int, len(rules))
+	for _, v := range violations {
+		counts[v.Rule]++
+	}
+	for _, rule := range rules {
+		m.LintViolations.WithLabelValues(repository, rule.Name, string(rule.Severity)).Set(float64(counts[rule.Name]))
+	}
+}
+
+// RecordTransfer adds the transferred and decoded size of a downloaded index
+func (m *Metrics) RecordTransfer(repository string, transferred, decoded int64) {
+	m.IndexTransferred.WithLabelValues(repository).Add(float64(transferred))
+	m.IndexDecoded.WithLabelValues(repository).Add(float64(decoded))
+}
+
+// UpdateIndex sets the size and freshness metrics of a repository's index.
+// Series for a missing generated field or Last-Modified header are removed rather than set to zero.
+func (m *Metrics) UpdateIndex(repository string, size int64, index *analyzer.HelmIndex, lastModified time.Time) {
+	m.IndexSize.WithLabelValues(repository).Set(float64(size))
+
+	if index.Generated.IsZero() {
+		m.IndexGenerated.DeleteLabelValues(repository)
+	} else {
+		m.IndexGenerated.WithLabelValues(repository).Set(float64(index.Generated.Unix()))
+	}
+
+	if lastModified.IsZero() {
+		m.IndexLastModified.DeleteLabelValues(repository)
+	} else {
+		m.IndexLastModified.WithLabelValues(repository).Set(float64(lastModified.Unix()))
+	}
+
+	m.IndexAPIVersion.DeletePartialMatch(prometheus.Labels{"repository": repository})
+	m.IndexAPIVersion.WithLabelValues(repository, index.APIVersion).Set(1)
+}
+
+// SetIndexRegenerated records whether a repository was analyzed from a generated index
+func (m *Metrics) SetIndexRegenerated(repository string, regenerated bool) {
+	value := 0.0
+	if regenerated {
+		value = 1
+	}
+	m.IndexRegenerated.WithLabelValues(repository).Set(value)
 }
 
 // RecordError increments the error counter for a repository
@@ -0,0 +1,306 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// seriesOf counts the series of a collector that belong to a repository
+func seriesOf(t *testing.T, c prometheus.Collector, repository string) int {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 1000)
+	c.Collect(ch)
+	close(ch)
+
+	n := 0
+	for metric := range ch {
+		var pb dto.Metric
+		if err := metric.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		for _, label := range pb.GetLabel() {
+			if label.GetName() == "repository" && label.GetValue() == repository {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func chartAnalysis(charts int) *analyzer.ChartAnalysis {
+	analysis := &analyzer.ChartAnalysis{TotalCharts: charts}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < charts; i++ {
+		created := base.Add(time.Duration(i) * time.Hour)
+		analysis.ChartsInfo = append(analysis.ChartsInfo, analyzer.ChartInfo{
+			Name:          fmt.Sprintf("chart-%d", i),
+			VersionCount:  1,
+			OldestVersion: created,
+			NewestVersion: created,
+			MedianVersion: created,
+		})
+	}
+	return analysis
+}
+
+func TestUpdate_SeriesLimits(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	// Each chart exports 5 series: versions, deprecated and three age gauges
+	m.SetSeriesLimits(SeriesLimits{Global: 40, PerRepository: map[string]int{"small": 10}})
+
+	m.Update("small", chartAnalysis(4))
+	if got := seriesOf(t, m.ChartVersions, "small"); got != 2 {
+		t.Errorf("Expected 2 charts within the repository limit, got %d", got)
+	}
+	// The counter sums the series dropped by every update, the gauge reports the current count
+	m.Update("small", chartAnalysis(4))
+	if got := testutil.ToFloat64(m.SeriesDropped.WithLabelValues("small")); got != 20 {
+		t.Errorf("Expected 20 dropped series over two updates, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.SeriesWithheld.WithLabelValues("small")); got != 10 {
+		t.Errorf("Expected 10 withheld series, got %v", got)
+	}
+	// The newest charts are kept
+	if got := testutil.ToFloat64(m.ChartVersions.WithLabelValues("small", "chart-3")); got != 1 {
+		t.Errorf("Expected the newest chart to be kept")
+	}
+
+	// The global limit leaves 30 series for the second repository
+	m.Update("large", chartAnalysis(10))
+	if got := seriesOf(t, m.ChartAgeNewest, "large"); got != 6 {
+		t.Errorf("Expected 6 charts within the global limit, got %d", got)
+	}
+
+	// Aggregate mode drops every per-chart series of a repository over its limit
+	m.SetSeriesLimits(SeriesLimits{PerRepository: map[string]int{"large": 10}, Aggregate: true})
+	m.Update("large", chartAnalysis(10))
+	if got := seriesOf(t, m.ChartAgeNewest, "large"); got != 0 {
+		t.Errorf("Expected no per-chart series in aggregate mode, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.ChartsTotal.WithLabelValues("large")); got != 10 {
+		t.Errorf("Expected repository totals to be kept, got %v", got)
+	}
+
+	// Without limits everything is exported again
+	m.SetSeriesLimits(SeriesLimits{})
+	m.Update("large", chartAnalysis(10))
+	if got := seriesOf(t, m.ChartAgeNewest, "large"); got != 10 {
+		t.Errorf("Expected all charts without limits, got %d", got)
+	}
+}
+
+func TestUpdate_GlobalLimitIgnoresScrapeOrder(t *testing.T) {
+	exported := func(order []string) map[string]int {
+		m := NewMetrics(prometheus.NewRegistry())
+		m.SetSeriesLimits(SeriesLimits{Global: 60})
+		analyses := map[string]*analyzer.ChartAnalysis{"alpha": chartAnalysis(8), "beta": chartAnalysis(8)}
+		// Two rounds let every repository give back series beyond its share
+		for round := 0; round < 2; round++ {
+			for _, repo := range order {
+				m.Update(repo, analyses[repo])
+			}
+		}
+		return map[string]int{"alpha": seriesOf(t, m.ChartVersions, "alpha"), "beta": seriesOf(t, m.ChartVersions, "beta")}
+	}
+
+	// The limit is handed out in name order: alpha gets its 40 series, beta the other 20
+	expected := map[string]int{"alpha": 8, "beta": 4}
+	for _, order := range [][]string{{"alpha", "beta"}, {"beta", "alpha"}} {
+		if got := exported(order); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Scrape order %v: expected charts %v, got %v", order, expected, got)
+		}
+	}
+
+	// The global limit is not exceeded while a repository gives back series
+	m := NewMetrics(prometheus.NewRegistry())
+	m.SetSeriesLimits(SeriesLimits{Global: 60})
+	m.Update("beta", chartAnalysis(8))
+	m.Update("alpha", chartAnalysis(8))
+	if got := seriesOf(t, m.ChartVersions, "alpha") + seriesOf(t, m.ChartVersions, "beta"); got*5 > 60 {
+		t.Errorf("Expected at most 60 series, got %d", got*5)
+	}
+}
+
+func TestUpdate_DeletesOnlyStaleSeries(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	analysis := chartAnalysis(3)
+	analysis.ChartsInfo[0].SignedVersions = 1
+	m.Update("repo", analysis)
+	if got := seriesOf(t, m.ChartSignatures, "repo"); got != 3 {
+		t.Fatalf("Expected signature series for the checked chart, got %d", got)
+	}
+
+	// chart-2 is removed, chart-1 loses its dates and chart-0 its provenance results
+	analysis = chartAnalysis(2)
+	analysis.ChartsInfo[1].OldestVersion = time.Time{}
+	m.Update("repo", analysis)
+
+	if got := seriesOf(t, m.ChartVersions, "repo"); got != 2 {
+		t.Errorf("Expected the removed chart's series to be deleted, got %d charts", got)
+	}
+	if got := seriesOf(t, m.ChartAgeOldest, "repo"); got != 1 {
+		t.Errorf("Expected the oldest age of chart-1 to be deleted, got %d series", got)
+	}
+	if got := seriesOf(t, m.ChartSignatures, "repo"); got != 0 {
+		t.Errorf("Expected unchecked signatures to be deleted, got %d series", got)
+	}
+	if got := testutil.ToFloat64(m.ChartAgeNewest.WithLabelValues("repo", "chart-0")); got == 0 {
+		t.Error("Expected the kept chart's series to remain")
+	}
+}
+
+func TestUpdateCombinedAges(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	var dates []time.Time
+	for i := int64(1); i <= 10; i++ {
+		dates = append(dates, time.Unix(i*100, 0))
+	}
+
+	m.UpdateCombinedAges(dates)
+	for quantile, expected := range map[string]float64{"0.1": 200, "0.5": 600, "0.9": 1000} {
+		if got := testutil.ToFloat64(m.CombinedAgeQuantile.WithLabelValues(quantile)); got != expected {
+			t.Errorf("Quantile %s: expected %v, got %v", quantile, expected, got)
+		}
+	}
+
+	m.UpdateCombinedAges(nil)
+	if got := testutil.CollectAndCount(m.CombinedAgeQuantile); got != 0 {
+		t.Errorf("Expected no quantiles without dates, got %d", got)
+	}
+}
+
+func TestUpdate_Deprecated(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	analysis := chartAnalysis(3)
+	analysis.ChartsInfo[1].Deprecated = true
+	analysis.DeprecatedCharts = 1
+	m.Update("repo", analysis)
+
+	for chart, expected := range map[string]float64{"chart-0": 0, "chart-1": 1, "chart-2": 0} {
+		if got := testutil.ToFloat64(m.ChartDeprecated.WithLabelValues("repo", chart)); got != expected {
+			t.Errorf("%s: expected deprecated %v, got %v", chart, expected, got)
+		}
+	}
+	if got := testutil.ToFloat64(m.DeprecatedCharts.WithLabelValues("repo")); got != 1 {
+		t.Errorf("Expected 1 deprecated chart, got %v", got)
+	}
+
+	// Undeprecating a chart resets both
+	m.Update("repo", chartAnalysis(3))
+	if got := testutil.ToFloat64(m.ChartDeprecated.WithLabelValues("repo", "chart-1")); got != 0 {
+		t.Errorf("Expected chart-1 to be no longer deprecated, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.DeprecatedCharts.WithLabelValues("repo")); got != 0 {
+		t.Errorf("Expected no deprecated charts, got %v", got)
+	}
+}
+
+func TestUpdateIndex(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	generated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lastModified := time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)
+
+	m.UpdateIndex("repo", 4096, &analyzer.HelmIndex{APIVersion: "v1", Generated: generated}, lastModified)
+	if got := testutil.ToFloat64(m.IndexSize.WithLabelValues("repo")); got != 4096 {
+		t.Errorf("Expected size 4096, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.IndexGenerated.WithLabelValues("repo")); got != float64(generated.Unix()) {
+		t.Errorf("Expected generated timestamp %d, got %v", generated.Unix(), got)
+	}
+	if got := testutil.ToFloat64(m.IndexLastModified.WithLabelValues("repo")); got != float64(lastModified.Unix()) {
+		t.Errorf("Expected last-modified timestamp %d, got %v", lastModified.Unix(), got)
+	}
+	if got := testutil.ToFloat64(m.IndexAPIVersion.WithLabelValues("repo", "v1")); got != 1 {
+		t.Errorf("Expected api_version_info v1 to be 1, got %v", got)
+	}
+
+	// An index without apiVersion, generated or Last-Modified replaces the previous series
+	m.UpdateIndex("repo", 10, &analyzer.HelmIndex{}, time.Time{})
+	if got := seriesOf(t, m.IndexAPIVersion, "repo"); got != 1 {
+		t.Fatalf("Expected a single api_version_info series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.IndexAPIVersion.WithLabelValues("repo", "")); got != 1 {
+		t.Errorf("Expected api_version_info with an empty version, got %v", got)
+	}
+	if got := seriesOf(t, m.IndexGenerated, "repo"); got != 0 {
+		t.Errorf("Expected no generated timestamp, got %d series", got)
+	}
+	if got := seriesOf(t, m.IndexLastModified, "repo"); got != 0 {
+		t.Errorf("Expected no last-modified timestamp, got %d series", got)
+	}
+	if got := testutil.ToFloat64(m.IndexSize.WithLabelValues("repo")); got != 10 {
+		t.Errorf("Expected size 10, got %v", got)
+	}
+}
+
+func TestSelfMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := NewMetrics(registry)
+
+	m.SetBuildInfo("1.2.3", "abc123")
+	m.SetBuildInfo("1.2.4", "def456")
+	if got := testutil.CollectAndCount(m.BuildInfo); got != 1 {
+		t.Errorf("Expected a single build_info series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.BuildInfo.WithLabelValues("1.2.4", "def456", runtime.Version())); got != 1 {
+		t.Errorf("Expected build_info 1 with the latest version, got %v", got)
+	}
+
+	handler := m.InstrumentHandler("health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	for _, path := range []string{"/", "/", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	counts := make(map[string]uint64)
+	for _, family := range families {
+		if family.GetName() != "helm_repo_exporter_http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			counts[labels["handler"]+" "+labels["code"]] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	if expected := map[string]uint64{"health 200": 2, "health 404": 1}; fmt.Sprint(counts) != fmt.Sprint(expected) {
+		t.Errorf("Expected request counts %v, got %v", expected, counts)
+	}
+}
+
+func TestNewMetrics_SeparateRegistries(t *testing.T) {
+	// Each instance registers with its own registry, so several can coexist, e.g. in tests
+	first, second := prometheus.NewRegistry(), prometheus.NewRegistry()
+	a, b := NewMetrics(first), NewMetrics(second)
+
+	a.Update("repo", chartAnalysis(2))
+	b.Update("repo", chartAnalysis(5))
+
+	if got := testutil.ToFloat64(a.ChartsTotal.WithLabelValues("repo")); got != 2 {
+		t.Errorf("Expected 2 charts in the first registry, got %v", got)
+	}
+	if got := testutil.ToFloat64(b.ChartsTotal.WithLabelValues("repo")); got != 5 {
+		t.Errorf("Expected 5 charts in the second registry, got %v", got)
+	}
+	if n, err := testutil.GatherAndCount(first, "helm_repo_chart_versions"); err != nil || n != 2 {
+		t.Errorf("Expected 2 chart series in the first registry, got %d (%v)", n, err)
+	}
+}
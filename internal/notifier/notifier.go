@@ -0,0 +1,314 @@
+// Package notifier sends new chart versions to webhooks
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/logging"
+	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+)
+
+// defaultTemplate is the body of generic webhooks without a template
+const defaultTemplate = `{"subscription": {{json .Subscription}}, "versions": {{json .Versions}}}`
+
+// errPermanent marks delivery failures that retrying cannot fix
+var errPermanent = errors.New("permanent failure")
+
+type subscription struct {
+	config.Subscription
+	repositories map[string]bool // nil for every repository
+	filter       *analyzer.ChartFilter
+	template     *template.Template // generic format only
+}
+
+// matches reports whether a new version is selected by the subscription
+func (s *subscription) matches(v analyzer.NewVersion) bool {
+	if s.repositories != nil && !s.repositories[v.Repository] {
+		return false
+	}
+	if s.filter == nil {
+		return true
+	}
+	kept, ok := s.filter.Filter(v.Chart, []analyzer.ChartVersionInfo{{Name: v.Chart, Version: v.Version}})
+	return ok && len(kept) > 0
+}
+
+// Notifier batches new chart versions per subscription and delivers them with retries.
+// Batches that cannot be delivered are written to the dead-letter file, or logged.
+type Notifier struct {
+	subscriptions []*subscription
+	client        *http.Client
+	interval      time.Duration
+	maxRetries    int
+	backoff       time.Duration
+
+	mu         sync.Mutex
+	pending    map[*subscription][]analyzer.NewVersion
+	deadLetter io.WriteCloser // nil without a dead-letter file
+}
+
+// New creates a notifier for the configured subscriptions
+func New(cfg *config.NotificationsConfig, client *http.Client) (*Notifier, error) {
+	n := &Notifier{
+		client:     client,
+		interval:   cfg.BatchInterval,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+		pending:    make(map[*subscription][]analyzer.NewVersion),
+	}
+
+	for _, sub := range cfg.Subscriptions {
+		s := &subscription{Subscription: sub}
+		if len(sub.Repositories) > 0 {
+			s.repositories = make(map[string]bool, len(sub.Repositories))
+			for _, repo := range sub.Repositories {
+				s.repositories[repo] = true
+			}
+		}
+
+		var err error
+		s.filter, err = analyzer.NewChartFilter(sub.Charts.Include, sub.Charts.Exclude, sub.Charts.Versions)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %q: %w", sub.Name, err)
+		}
+
+		if sub.Format == "" || sub.Format == config.NotifyFormatGeneric {
+			text := sub.Template
+			if text == "" {
+				text = defaultTemplate
+			}
+			s.template, err = template.New(sub.Name).Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("subscription %q: invalid template: %w", sub.Name, err)
+			}
+		}
+		n.subscriptions = append(n.subscriptions, s)
+	}
+
+	if cfg.DeadLetterFile != "" {
+		f, err := os.OpenFile(cfg.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // #nosec G304 -- path comes from operator configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+		}
+		n.deadLetter = f
+	}
+
+	return n, nil
+}
+
+// Notify queues the new versions for every subscription selecting them
+func (n *Notifier) Notify(versions []analyzer.NewVersion) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, s := range n.subscriptions {
+		for _, v := range versions {
+			if s.matches(v) {
+				n.pending[s] = append(n.pending[s], v)
+			}
+		}
+	}
+}
+
+// Run delivers the queued versions every batch interval until ctx is done
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n.Flush(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Flush delivers the queued versions now, one batch per subscription
+func (n *Notifier) Flush(ctx context.Context) {
+	n.mu.Lock()
+	batches := n.pending
+	n.pending = make(map[*subscription][]analyzer.NewVersion)
+	n.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for s, versions := range batches {
+		wg.Add(1)
+		go func(s *subscription, versions []analyzer.NewVersion) {
+			defer wg.Done()
+			if err := n.deliver(ctx, s, versions); err != nil {
+				n.deadLetterBatch(s, versions, err)
+				return
+			}
+			slog.Info("Notification sent", "subscription", s.Name, "versions", len(versions))
+		}(s, versions)
+	}
+	wg.Wait()
+}
+
+// Close releases the dead-letter file
+func (n *Notifier) Close() error {
+	if n.deadLetter == nil {
+		return nil
+	}
+	return n.deadLetter.Close()
+}
+
+// deliver sends a batch, retrying with exponential backoff on network errors, 429 and 5xx responses
+func (n *Notifier) deliver(ctx context.Context, s *subscription, versions []analyzer.NewVersion) error {
+	body, err := s.payload(versions)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		err = n.post(ctx, s, body)
+		if err == nil || errors.Is(err, errPermanent) || attempt == n.maxRetries {
+			return err
+		}
+		slog.Warn("Notification failed, retrying", "subscription", s.Name, "retry_in", backoff, logging.ErrorReason(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, s *subscription, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: unexpected status code: %d", errPermanent, resp.StatusCode)
+	}
+}
+
+// deadLetterBatch records a batch that could not be delivered
+func (n *Notifier) deadLetterBatch(s *subscription, versions []analyzer.NewVersion, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.deadLetter == nil {
+		// The log is the dead letter, so it lists every undelivered version
+		listed := make([]string, len(versions))
+		for i, v := range versions {
+			listed[i] = v.Repository + "/" + v.Chart + " " + v.Version
+		}
+		slog.Error("Notification dropped", "subscription", s.Name, "versions", listed, logging.ErrorReason(err))
+		return
+	}
+	slog.Error("Notification dropped, written to the dead-letter file", "subscription", s.Name, "versions", len(versions), logging.ErrorReason(err))
+
+	line, _ := json.Marshal(struct {
+		Time         time.Time             `json:"time"`
+		Subscription string                `json:"subscription"`
+		Error        string                `json:"error"`
+		Versions     []analyzer.NewVersion `json:"versions"`
+	}{time.Now().UTC(), s.Name, err.Error(), versions})
+	if _, err := n.deadLetter.Write(append(line, '\n')); err != nil {
+		slog.Error("Failed to write the dead-letter file", logging.ErrorReason(err))
+	}
+}
+
+// payload renders the request body of a batch in the subscription's format
+func (s *subscription) payload(versions []analyzer.NewVersion) ([]byte, error) {
+	switch s.Format {
+	case config.NotifyFormatSlack:
+		return json.Marshal(map[string]string{"text": summary(versions, "*", "• ")})
+	case config.NotifyFormatTeams:
+		return json.Marshal(teamsMessage(versions))
+	}
+
+	var buf bytes.Buffer
+	if err := s.template.Execute(&buf, struct {
+		Subscription string
+		Versions     []analyzer.NewVersion
+	}{s.Name, versions}); err != nil {
+		return nil, fmt.Errorf("%w: failed to render template: %v", errPermanent, err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("%w: template did not render valid JSON", errPermanent)
+	}
+	return buf.Bytes(), nil
+}
+
+// maxListed caps the versions listed in chat messages
+const maxListed = 30
+
+// summary describes a batch in Markdown, with bold title and bulleted lines
+func summary(versions []analyzer.NewVersion, bold, bullet string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%d new chart version(s)%s\n", bold, len(versions), bold)
+	for i, v := range versions {
+		if i == maxListed {
+			fmt.Fprintf(&b, "%s…and %d more\n", bullet, len(versions)-maxListed)
+			break
+		}
+		fmt.Fprintf(&b, "%s%s/%s %s", bullet, v.Repository, v.Chart, v.Version)
+		if v.NewChart {
+			b.WriteString(" (new chart)")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// teamsMessage wraps the summary in an Adaptive Card, as accepted by Teams incoming webhooks and workflows
+func teamsMessage(versions []analyzer.NewVersion) map[string]any {
+	lines := strings.Split(strings.TrimSuffix(summary(versions, "", "- "), "\n"), "\n")
+	body := []map[string]any{
+		{"type": "TextBlock", "text": lines[0], "weight": "Bolder", "size": "Medium", "wrap": true},
+	}
+	if len(lines) > 1 {
+		body = append(body, map[string]any{"type": "TextBlock", "text": strings.Join(lines[1:], "\n"), "wrap": true})
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
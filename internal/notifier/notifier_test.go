@@ -0,0 +1,189 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+)
+
+// webhook is a local stand-in for a webhook endpoint that fails the first failures requests
+type webhook struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	status   int
+	requests int
+	bodies   []string
+}
+
+func newWebhook(t *testing.T, failures, status int) *webhook {
+	w := &webhook{failures: failures, status: status}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.requests++
+		if w.requests <= w.failures {
+			rw.WriteHeader(w.status)
+			return
+		}
+		w.bodies = append(w.bodies, string(body))
+	}))
+	t.Cleanup(w.Close)
+	return w
+}
+
+func (w *webhook) received() (int, []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.requests, append([]string(nil), w.bodies...)
+}
+
+var testVersions = []analyzer.NewVersion{
+	{Repository: "stable", Chart: "nginx", Version: "1.2.0"},
+	{Repository: "stable", Chart: "nginx", Version: "2.0.0-rc.1"},
+	{Repository: "stable", Chart: "redis", Version: "7.0.0", NewChart: true},
+	{Repository: "incubator", Chart: "nginx", Version: "0.1.0"},
+}
+
+func newTestNotifier(t *testing.T, cfg config.NotificationsConfig) *Notifier {
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	cfg.RetryBackoff = time.Millisecond
+	n, err := New(&cfg, &http.Client{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { _ = n.Close() })
+	return n
+}
+
+func TestNotifier_Formats(t *testing.T) {
+	generic := newWebhook(t, 0, 0)
+	templated := newWebhook(t, 0, 0)
+	slack := newWebhook(t, 0, 0)
+	teams := newWebhook(t, 0, 0)
+
+	n := newTestNotifier(t, config.NotificationsConfig{Subscriptions: []config.Subscription{
+		{Name: "generic", URL: generic.URL, Format: config.NotifyFormatGeneric},
+		{Name: "templated", URL: templated.URL, Format: config.NotifyFormatGeneric,
+			Template: `{"count": {{len .Versions}}, "first": {{json (index .Versions 0).Chart}}}`},
+		{Name: "slack", URL: slack.URL, Format: config.NotifyFormatSlack},
+		{Name: "teams", URL: teams.URL, Format: config.NotifyFormatTeams},
+	}})
+	n.Notify(testVersions)
+	n.Flush(context.Background())
+
+	_, bodies := generic.received()
+	var payload struct {
+		Subscription string                `json:"subscription"`
+		Versions     []analyzer.NewVersion `json:"versions"`
+	}
+	if len(bodies) != 1 || json.Unmarshal([]byte(bodies[0]), &payload) != nil {
+		t.Fatalf("Expected one JSON batch, got %q", bodies)
+	}
+	if payload.Subscription != "generic" || len(payload.Versions) != len(testVersions) {
+		t.Errorf("Unexpected generic payload %+v", payload)
+	}
+
+	if _, bodies := templated.received(); len(bodies) != 1 || bodies[0] != `{"count": 4, "first": "nginx"}` {
+		t.Errorf("Unexpected templated payload %q", bodies)
+	}
+
+	_, bodies = slack.received()
+	var message struct{ Text string }
+	if len(bodies) != 1 || json.Unmarshal([]byte(bodies[0]), &message) != nil || !strings.Contains(message.Text, "stable/redis 7.0.0 (new chart)") {
+		t.Errorf("Unexpected Slack payload %q", bodies)
+	}
+
+	_, bodies = teams.received()
+	if len(bodies) != 1 || !strings.Contains(bodies[0], `"AdaptiveCard"`) || !strings.Contains(bodies[0], "stable/nginx 1.2.0") {
+		t.Errorf("Unexpected Teams payload %q", bodies)
+	}
+}
+
+func TestNotifier_Filters(t *testing.T) {
+	hook := newWebhook(t, 0, 0)
+	n := newTestNotifier(t, config.NotificationsConfig{Subscriptions: []config.Subscription{{
+		Name:         "stable-nginx",
+		URL:          hook.URL,
+		Repositories: []string{"stable"},
+		Charts:       config.ChartFilterConfig{Include: []string{"nginx"}, Versions: ">= 1.0.0"},
+	}}})
+	n.Notify(testVersions)
+	n.Flush(context.Background())
+
+	_, bodies := hook.received()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected one batch, got %d", len(bodies))
+	}
+	var payload struct{ Versions []analyzer.NewVersion }
+	if err := json.Unmarshal([]byte(bodies[0]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Versions) != 1 || payload.Versions[0].Version != "1.2.0" {
+		t.Errorf("Expected only stable/nginx 1.2.0, got %+v", payload.Versions)
+	}
+}
+
+func TestNotifier_RetriesAndDeadLetter(t *testing.T) {
+	flaky := newWebhook(t, 2, http.StatusServiceUnavailable)
+	down := newWebhook(t, 100, http.StatusBadGateway)
+	rejected := newWebhook(t, 100, http.StatusBadRequest)
+	deadLetter := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+
+	n := newTestNotifier(t, config.NotificationsConfig{
+		DeadLetterFile: deadLetter,
+		Subscriptions: []config.Subscription{
+			{Name: "flaky", URL: flaky.URL},
+			{Name: "down", URL: down.URL},
+			{Name: "rejected", URL: rejected.URL},
+		},
+	})
+	n.Notify(testVersions[:1])
+	n.Flush(context.Background())
+
+	if requests, bodies := flaky.received(); requests != 3 || len(bodies) != 1 {
+		t.Errorf("Expected delivery on the third attempt, got %d request(s) and %d delivery", requests, len(bodies))
+	}
+	if requests, _ := down.received(); requests != 3 {
+		t.Errorf("Expected 3 attempts on a server error, got %d", requests)
+	}
+	if requests, _ := rejected.received(); requests != 1 {
+		t.Errorf("Expected no retry on a client error, got %d request(s)", requests)
+	}
+
+	data, err := os.ReadFile(deadLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 dead letters, got %q", data)
+	}
+	for _, line := range lines {
+		var entry struct {
+			Subscription string
+			Versions     []analyzer.NewVersion
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || len(entry.Versions) != 1 {
+			t.Errorf("Unexpected dead letter %q", line)
+		}
+		if entry.Subscription != "down" && entry.Subscription != "rejected" {
+			t.Errorf("Unexpected dead letter for %q", entry.Subscription)
+		}
+	}
+}
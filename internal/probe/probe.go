@@ -0,0 +1,186 @@
+// Package probe analyzes repositories on demand, in the style of the blackbox exporter
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/fetcher"
+	"github.com/obezpalko/helm-repo-exporter/internal/logging"
+	"github.com/obezpalko/helm-repo-exporter/internal/metrics"
+	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type module struct {
+	config.ProbeModule
+	tls *tls.Config // nil for the default transport
+}
+
+// Handler serves /probe?target=<index url>&module=<name>.
+// Every request fetches and analyzes the target and returns its metrics from a fresh registry,
+// labelled with the target as the repository.
+type Handler struct {
+	modules      map[string]module
+	targets      []string // Hosts probes without a module may fetch, empty allows any
+	timeout      time.Duration
+	maxIndexSize int64
+	linter       *analyzer.Linter
+}
+
+// NewHandler creates a probe handler with the given modules.
+// Probes without a module send no credentials and may only fetch the given target hosts.
+func NewHandler(modules map[string]config.ProbeModule, targets []string, timeout time.Duration, maxIndexSize int64) (*Handler, error) {
+	linter, err := analyzer.NewLinter(analyzer.DefaultLintRules(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Handler{
+		modules:      make(map[string]module, len(modules)),
+		targets:      targets,
+		timeout:      timeout,
+		maxIndexSize: maxIndexSize,
+		linter:       linter,
+	}
+	for name, cfg := range modules {
+		m := module{ProbeModule: cfg}
+		if cfg.TLS != nil {
+			m.tls, err = fetcher.NewTLSConfig(cfg.TLS)
+			if err != nil {
+				return nil, fmt.Errorf("probe module %q: %w", name, err)
+			}
+		}
+		h.modules[name] = m
+	}
+	return h, nil
+}
+
+// ServeHTTP probes the target and writes its metrics
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		http.Error(w, "target must be an http or https URL without credentials", http.StatusBadRequest)
+		return
+	}
+
+	// Probes without a module use a module without credentials
+	m := module{ProbeModule: config.ProbeModule{Targets: h.targets}}
+	if moduleName := r.URL.Query().Get("module"); moduleName != "" {
+		var ok bool
+		if m, ok = h.modules[moduleName]; !ok {
+			http.Error(w, fmt.Sprintf("unknown module %q", moduleName), http.StatusBadRequest)
+			return
+		}
+	}
+	if !hostAllowed(u.Hostname(), m.Targets) {
+		http.Error(w, fmt.Sprintf("target host %q is not allowed", u.Hostname()), http.StatusForbidden)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	success := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_success",
+		Help: "Whether the repository index was fetched and analyzed successfully",
+	})
+	duration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_duration_seconds",
+		Help: "Time taken to fetch and analyze the repository index",
+	})
+	registry.MustRegister(success, duration)
+	collector := metrics.NewMetrics(registry)
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err = h.probe(ctx, target, m, collector)
+	duration.Set(time.Since(start).Seconds())
+	if err != nil {
+		slog.Warn("Probe failed", logging.KeyURL, target, logging.ErrorReason(err))
+		collector.RecordError(target)
+	} else {
+		success.Set(1)
+	}
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// probe fetches, analyzes and lints the target's index into the given metrics
+func (h *Handler) probe(ctx context.Context, target string, m module, collector *metrics.Metrics) error {
+	maxIndexSize := m.MaxIndexSize
+	if maxIndexSize == 0 {
+		maxIndexSize = h.maxIndexSize
+	}
+	client := fetcher.NewClient(config.Repository{
+		Name:         target,
+		URL:          target,
+		Auth:         m.Auth,
+		MaxIndexSize: maxIndexSize,
+	}, h.timeout)
+	if m.tls != nil {
+		client.SetTLSConfig(m.tls)
+	}
+	client.AllowHosts(func(host string) bool { return hostAllowed(host, m.Targets) })
+	client.OnFetch(func(bytes int64) { collector.FetchedBytes.WithLabelValues(target).Add(float64(bytes)) })
+
+	body, err := client.OpenIndex(ctx)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	builder := analyzer.NewAnalysisBuilder(target, target)
+	var violations []analyzer.LintViolation
+	index, err := analyzer.WalkIndex(body, func(chartName string, versions []analyzer.ChartVersionInfo) error {
+		versions, _ = builder.AddChart(chartName, versions)
+		violations = append(violations, h.linter.LintEntry(chartName, versions, target, target)...)
+		return nil
+	})
+	collector.RecordTransfer(target, body.TransferredBytes(), body.DecodedBytes())
+	if err != nil {
+		return err
+	}
+
+	analysis := builder.Analysis()
+	analysis.LintViolations = append(h.linter.LintIndex(index, target, target), violations...)
+
+	collector.UpdateIndex(target, body.DecodedBytes(), index, body.LastModified())
+	collector.Update(target, analysis)
+	collector.UpdateLint(target, h.linter.Rules(), analysis.LintViolations)
+	collector.RecordSuccess(target)
+	return nil
+}
+
+// hostAllowed reports whether host matches one of the patterns: an exact name, or
+// "*.example.com" for any subdomain of example.com. Without patterns every host is allowed.
+func hostAllowed(host string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package probe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+)
+
+const testIndex = `apiVersion: v1
+entries:
+  app:
+  - name: app
+    version: 1.0.0
+    created: "2024-01-01T00:00:00Z"
+    urls:
+    - app-1.0.0.tgz
+generated: "2024-01-01T00:00:00Z"
+`
+
+func TestHandler(t *testing.T) {
+	var hosts []string // Host of every request, to check where credentials went
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		if strings.HasPrefix(r.URL.Path, "/redirect") {
+			_, port, _ := strings.Cut(r.Host, ":")
+			http.Redirect(w, r, "http://localhost:"+port+"/index.yaml", http.StatusFound)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "probe" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(testIndex))
+	}))
+	defer repo.Close()
+
+	auth := &config.AuthConfig{Basic: &config.BasicAuth{Username: "probe", Password: "secret"}}
+	handler, err := NewHandler(map[string]config.ProbeModule{
+		"private": {Auth: auth, Targets: []string{"127.0.0.1"}},
+		"default": {Auth: auth, Targets: []string{"127.0.0.1"}},
+	}, nil, 5*time.Second, config.DefaultMaxIndexSize)
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+
+	target := repo.URL + "/index.yaml"
+	tests := []struct {
+		name     string
+		query    url.Values
+		status   int
+		contains []string
+	}{
+		{
+			name:   "success",
+			query:  url.Values{"target": {target}, "module": {"private"}},
+			status: http.StatusOK,
+			contains: []string{
+				"probe_success 1",
+				"probe_duration_seconds ",
+				`helm_repo_charts_total{repository="` + target + `"} 1`,
+			},
+		},
+		{
+			name:     "missing auth",
+			query:    url.Values{"target": {target}},
+			status:   http.StatusOK,
+			contains: []string{"probe_success 0", `helm_repo_scrape_errors_total{repository="` + target + `"} 1`},
+		},
+		{
+			name:     "redirect to another host",
+			query:    url.Values{"target": {repo.URL + "/redirect"}, "module": {"private"}},
+			status:   http.StatusOK,
+			contains: []string{"probe_success 0"},
+		},
+		{name: "host not allowed", query: url.Values{"target": {"https://attacker.example.com/index.yaml"}, "module": {"private"}}, status: http.StatusForbidden},
+		{name: "credentials in target", query: url.Values{"target": {"http://user:pass@" + strings.TrimPrefix(target, "http://")}}, status: http.StatusBadRequest},
+		{name: "unknown module", query: url.Values{"target": {target}, "module": {"other"}}, status: http.StatusBadRequest},
+		{name: "missing target", query: url.Values{}, status: http.StatusBadRequest},
+		{name: "local target", query: url.Values{"target": {"file:///etc/passwd"}}, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?"+tt.query.Encode(), nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(rec.Body.String(), s) {
+					t.Errorf("Expected output to contain %q, got:\n%s", s, rec.Body.String())
+				}
+			}
+		})
+	}
+
+	for _, host := range hosts {
+		if strings.HasPrefix(host, "localhost") {
+			t.Errorf("Expected the redirect to another host to be refused, got a request to %s", host)
+		}
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	patterns := []string{"charts.example.com", "*.internal.example.com"}
+	for host, expected := range map[string]bool{
+		"charts.example.com":          true,
+		"CHARTS.example.com.":         true,
+		"a.internal.example.com":      true,
+		"internal.example.com":        false,
+		"evilinternal.example.com":    false,
+		"charts.example.com.evil.com": false,
+	} {
+		if got := hostAllowed(host, patterns); got != expected {
+			t.Errorf("%s: expected %v, got %v", host, expected, got)
+		}
+	}
+	if !hostAllowed("anything.example.com", nil) {
+		t.Error("Expected every host to be allowed without patterns")
+	}
+}
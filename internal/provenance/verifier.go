@@ -0,0 +1,241 @@
+package provenance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/fetcher"
+
+	// Helm itself signs and verifies charts with this package; the replacement
+	// suggested by the deprecation notice is not API compatible with .prov files.
+	"golang.org/x/crypto/openpgp"           //nolint:staticcheck // see above
+	"golang.org/x/crypto/openpgp/clearsign" //nolint:staticcheck // see above
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// maxConcurrentFetches limits parallel .prov downloads per repository
+	maxConcurrentFetches = 8
+
+	// unsignedRecheckInterval controls how long a missing .prov is remembered
+	// before it is requested again; signed and invalid results never change for
+	// a given digest and are cached for the lifetime of the process
+	unsignedRecheckInterval = time.Hour
+)
+
+// FileFetcher retrieves chart files from a repository
+type FileFetcher interface {
+	GetFile(ctx context.Context, fileURL string) ([]byte, error)
+}
+
+// Verifier checks chart provenance files against a trusted keyring
+type Verifier struct {
+	keyring openpgp.EntityList
+	fetcher FileFetcher
+
+	mu    sync.Mutex
+	cache map[string]cachedResult
+}
+
+type cachedResult struct {
+	status    analyzer.SignatureStatus
+	checkedAt time.Time
+}
+
+// sumCollection is the files section of a provenance message
+type sumCollection struct {
+	Files map[string]string `yaml:"files"`
+}
+
+// LoadKeyring reads an OpenPGP keyring from disk, accepting both binary and ASCII-armored formats
+func LoadKeyring(keyringPath string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(keyringPath) // #nosec G304 -- path comes from operator configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var keyring openpgp.EntityList
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: %w", keyringPath, err)
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("keyring %s contains no keys", keyringPath)
+	}
+
+	return keyring, nil
+}
+
+// NewVerifier creates a verifier using the given keyring
+func NewVerifier(keyring openpgp.EntityList, fileFetcher FileFetcher) *Verifier {
+	return &Verifier{
+		keyring: keyring,
+		fetcher: fileFetcher,
+		cache:   make(map[string]cachedResult),
+	}
+}
+
+// Verify checks every chart version in the analysis and records the results in place.
+// Versions whose provenance could not be fetched are left as SignatureUnknown.
+// Returns the first fetch error encountered, if any, for logging.
+func (v *Verifier) Verify(ctx context.Context, analysis *analyzer.ChartAnalysis) error {
+	type job struct {
+		chart   int
+		version int
+	}
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	for i := 0; i < maxConcurrentFetches; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				detail := &analysis.ChartsInfo[j.chart].VersionDetails[j.version]
+				status, err := v.VerifyVersion(ctx, *detail)
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+				}
+				detail.Signature = status
+			}
+		}()
+	}
+
+	for i := range analysis.ChartsInfo {
+		for j := range analysis.ChartsInfo[i].VersionDetails {
+			jobs <- job{chart: i, version: j}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := range analysis.ChartsInfo {
+		chart := &analysis.ChartsInfo[i]
+		chart.SignedVersions, chart.UnsignedVersions, chart.InvalidVersions = 0, 0, 0
+		for _, detail := range chart.VersionDetails {
+			switch detail.Signature {
+			case analyzer.SignatureSigned:
+				chart.SignedVersions++
+			case analyzer.SignatureUnsigned:
+				chart.UnsignedVersions++
+			case analyzer.SignatureInvalid:
+				chart.InvalidVersions++
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// VerifyVersion checks the provenance file of a single chart version
+func (v *Verifier) VerifyVersion(ctx context.Context, detail analyzer.VersionDetail) (analyzer.SignatureStatus, error) {
+	if detail.URL == "" {
+		return analyzer.SignatureUnknown, nil
+	}
+
+	key := detail.URL + "@" + detail.Digest
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && (cached.status != analyzer.SignatureUnsigned || time.Since(cached.checkedAt) < unsignedRecheckInterval) {
+		return cached.status, nil
+	}
+
+	status, err := v.verify(ctx, detail)
+	if err != nil {
+		return analyzer.SignatureUnknown, err
+	}
+
+	v.mu.Lock()
+	v.cache[key] = cachedResult{status: status, checkedAt: time.Now()}
+	v.mu.Unlock()
+
+	return status, nil
+}
+
+func (v *Verifier) verify(ctx context.Context, detail analyzer.VersionDetail) (analyzer.SignatureStatus, error) {
+	prov, err := v.fetcher.GetFile(ctx, detail.URL+".prov")
+	if errors.Is(err, fetcher.ErrNotFound) {
+		return analyzer.SignatureUnsigned, nil
+	}
+	if err != nil {
+		return analyzer.SignatureUnknown, err
+	}
+
+	block, _ := clearsign.Decode(prov)
+	if block == nil {
+		return analyzer.SignatureInvalid, nil
+	}
+	if _, err := openpgp.CheckDetachedSignature(v.keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+		return analyzer.SignatureInvalid, nil
+	}
+
+	sums, err := parseMessageBlock(block.Plaintext)
+	if err != nil {
+		return analyzer.SignatureInvalid, nil
+	}
+
+	expected, ok := sums.Files[tarballName(detail.URL)]
+	if !ok {
+		return analyzer.SignatureInvalid, nil
+	}
+
+	// The signature covers the checksum, so the tarball that is actually served must be
+	// hashed; the index digest is only as trustworthy as the index itself
+	tarball, err := v.fetcher.GetFile(ctx, detail.URL)
+	if err != nil {
+		return analyzer.SignatureUnknown, err
+	}
+	sum := sha256.Sum256(tarball)
+	digest := hex.EncodeToString(sum[:])
+
+	if !strings.EqualFold(expected, "sha256:"+digest) {
+		return analyzer.SignatureInvalid, nil
+	}
+	if detail.Digest != "" && !strings.EqualFold(detail.Digest, digest) {
+		return analyzer.SignatureInvalid, nil
+	}
+
+	return analyzer.SignatureSigned, nil
+}
+
+// parseMessageBlock extracts the file checksums from a provenance message.
+// The message is the chart's Chart.yaml followed by a "..." separator and a files map.
+func parseMessageBlock(plaintext []byte) (*sumCollection, error) {
+	parts := bytes.Split(plaintext, []byte("\n...\n"))
+	if len(parts) < 2 {
+		return nil, errors.New("provenance message has no files section")
+	}
+
+	var sums sumCollection
+	if err := yaml.Unmarshal(parts[1], &sums); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance files section: %w", err)
+	}
+	return &sums, nil
+}
+
+// tarballName returns the file name of the chart archive referenced by chartURL
+func tarballName(chartURL string) string {
+	if u, err := url.Parse(chartURL); err == nil && u.Path != "" {
+		return path.Base(u.Path)
+	}
+	return path.Base(chartURL)
+}
@@ -0,0 +1,162 @@
+package provenance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/fetcher"
+	"golang.org/x/crypto/openpgp"           //nolint:staticcheck // matches verifier.go
+	"golang.org/x/crypto/openpgp/clearsign" //nolint:staticcheck // matches verifier.go
+)
+
+// mapFetcher serves files from memory and returns ErrNotFound for anything else
+type mapFetcher map[string][]byte
+
+func (m mapFetcher) GetFile(_ context.Context, fileURL string) ([]byte, error) {
+	if data, ok := m[fileURL]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s: %w", fileURL, fetcher.ErrNotFound)
+}
+
+func signProvenance(t *testing.T, signer *openpgp.Entity, tarballName, digest string) []byte {
+	t.Helper()
+
+	message := fmt.Sprintf("apiVersion: v2\nname: demo\nversion: 1.0.0\n\n...\nfiles:\n  %s: sha256:%s\n", tarballName, digest)
+
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, signer.PrivateKey, nil)
+	if err != nil {
+		t.Fatalf("Failed to create clearsign encoder: %v", err)
+	}
+	if _, err := w.Write([]byte(message)); err != nil {
+		t.Fatalf("Failed to write provenance message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close clearsign encoder: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifier_VerifyVersion(t *testing.T) {
+	trusted, err := openpgp.NewEntity("Trusted", "", "trusted@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create trusted key: %v", err)
+	}
+	untrusted, err := openpgp.NewEntity("Untrusted", "", "untrusted@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create untrusted key: %v", err)
+	}
+
+	tarball := []byte("chart archive contents")
+	digest := sha256Hex(tarball)
+
+	const base = "https://charts.example.com/"
+	files := mapFetcher{
+		base + "signed-1.0.0.tgz":         tarball,
+		base + "signed-1.0.0.tgz.prov":    signProvenance(t, trusted, "signed-1.0.0.tgz", digest),
+		base + "nodigest-1.0.0.tgz":       tarball,
+		base + "nodigest-1.0.0.tgz.prov":  signProvenance(t, trusted, "nodigest-1.0.0.tgz", digest),
+		base + "wrongkey-1.0.0.tgz":       tarball,
+		base + "wrongkey-1.0.0.tgz.prov":  signProvenance(t, untrusted, "wrongkey-1.0.0.tgz", digest),
+		base + "mismatch-1.0.0.tgz":       tarball,
+		base + "mismatch-1.0.0.tgz.prov":  signProvenance(t, trusted, "mismatch-1.0.0.tgz", digest),
+		base + "tampered-1.0.0.tgz":       []byte("replaced chart archive"),
+		base + "tampered-1.0.0.tgz.prov":  signProvenance(t, trusted, "tampered-1.0.0.tgz", digest),
+		base + "garbage-1.0.0.tgz.prov":   []byte("not a signature"),
+		base + "othername-1.0.0.tgz":      tarball,
+		base + "othername-1.0.0.tgz.prov": signProvenance(t, trusted, "different-1.0.0.tgz", digest),
+		base + "notarball-1.0.0.tgz.prov": signProvenance(t, trusted, "notarball-1.0.0.tgz", digest),
+	}
+
+	verifier := NewVerifier(openpgp.EntityList{trusted}, files)
+
+	tests := []struct {
+		name     string
+		detail   analyzer.VersionDetail
+		expected analyzer.SignatureStatus
+	}{
+		{"valid signature and digest", analyzer.VersionDetail{URL: base + "signed-1.0.0.tgz", Digest: digest}, analyzer.SignatureSigned},
+		{"digest computed from tarball", analyzer.VersionDetail{URL: base + "nodigest-1.0.0.tgz"}, analyzer.SignatureSigned},
+		{"missing prov file", analyzer.VersionDetail{URL: base + "missing-1.0.0.tgz", Digest: digest}, analyzer.SignatureUnsigned},
+		{"signed by untrusted key", analyzer.VersionDetail{URL: base + "wrongkey-1.0.0.tgz", Digest: digest}, analyzer.SignatureInvalid},
+		{"index digest mismatch", analyzer.VersionDetail{URL: base + "mismatch-1.0.0.tgz", Digest: "deadbeef"}, analyzer.SignatureInvalid},
+		// The index and provenance agree, but the served tarball was replaced
+		{"tampered tarball", analyzer.VersionDetail{URL: base + "tampered-1.0.0.tgz", Digest: digest}, analyzer.SignatureInvalid},
+		{"not a clearsigned document", analyzer.VersionDetail{URL: base + "garbage-1.0.0.tgz", Digest: digest}, analyzer.SignatureInvalid},
+		{"prov for a different file", analyzer.VersionDetail{URL: base + "othername-1.0.0.tgz", Digest: digest}, analyzer.SignatureInvalid},
+		{"no URL", analyzer.VersionDetail{}, analyzer.SignatureUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := verifier.VerifyVersion(context.Background(), tt.detail)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if status != tt.expected {
+				t.Errorf("Expected status %q, got %q", tt.expected, status)
+			}
+		})
+	}
+
+	// A signed version whose tarball cannot be downloaded stays unknown
+	status, err := verifier.VerifyVersion(context.Background(), analyzer.VersionDetail{URL: base + "notarball-1.0.0.tgz", Digest: digest})
+	if err == nil || status != analyzer.SignatureUnknown {
+		t.Errorf("Expected an error and status %q, got %q (%v)", analyzer.SignatureUnknown, status, err)
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVerifier_VerifyAnalysis(t *testing.T) {
+	trusted, err := openpgp.NewEntity("Trusted", "", "trusted@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create trusted key: %v", err)
+	}
+
+	tarball := []byte("chart archive contents")
+	digest := sha256Hex(tarball)
+
+	const base = "https://charts.example.com/"
+	files := mapFetcher{
+		base + "app-1.0.0.tgz":      tarball,
+		base + "app-1.0.0.tgz.prov": signProvenance(t, trusted, "app-1.0.0.tgz", digest),
+		base + "app-1.1.0.tgz":      tarball,
+		base + "app-1.1.0.tgz.prov": signProvenance(t, trusted, "app-1.1.0.tgz", digest),
+	}
+
+	analysis := &analyzer.ChartAnalysis{
+		ChartsInfo: []analyzer.ChartInfo{
+			{
+				Name: "app",
+				VersionDetails: []analyzer.VersionDetail{
+					{Version: "1.0.0", URL: base + "app-1.0.0.tgz", Digest: digest},
+					{Version: "1.1.0", URL: base + "app-1.1.0.tgz", Digest: "other"},
+					{Version: "1.2.0", URL: base + "app-1.2.0.tgz", Digest: digest},
+				},
+			},
+		},
+	}
+
+	if err := NewVerifier(openpgp.EntityList{trusted}, files).Verify(context.Background(), analysis); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	chart := analysis.ChartsInfo[0]
+	if chart.SignedVersions != 1 || chart.InvalidVersions != 1 || chart.UnsignedVersions != 1 {
+		t.Errorf("Expected 1 signed, 1 invalid, 1 unsigned; got %d, %d, %d",
+			chart.SignedVersions, chart.InvalidVersions, chart.UnsignedVersions)
+	}
+	if chart.SignatureSummary() != analyzer.SignatureInvalid {
+		t.Errorf("Expected summary %q, got %q", analyzer.SignatureInvalid, chart.SignatureSummary())
+	}
+}
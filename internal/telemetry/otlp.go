@@ -0,0 +1,161 @@
+// Package telemetry exports the exporter's metrics and traces over OTLP
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/logging"
+	"github.com/obezpalko/helm-repo-exporter/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	promBridge "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/obezpalko/helm-repo-exporter"
+
+// redactor removes credentials from span attributes and errors; nil only removes URL credentials
+var redactor atomic.Pointer[logging.Redactor]
+
+// SetSecrets sets the configured secrets removed from exported spans
+func SetSecrets(secrets []string) {
+	redactor.Store(logging.NewRedactor(secrets))
+}
+
+// Tracer returns the tracer used for scrape spans.
+// Spans are discarded unless Setup has installed an exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartRepositorySpan starts a span for a stage of a repository scrape
+func StartRepositorySpan(ctx context.Context, name, repository, repoURL string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(
+		attribute.String("helm.repository.name", repository),
+		attribute.String("helm.repository.url", publicURL(repoURL)),
+	))
+}
+
+// EndSpan records err, if any, on the span and ends it. Credentials are removed from the error text.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		message := redactor.Load().Redact(err.Error())
+		span.RecordError(errors.New(message))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
+
+// publicURL removes credentials and configured secrets from a URL
+func publicURL(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+		u.User = nil
+		rawURL = u.String()
+	}
+	return redactor.Load().Redact(rawURL)
+}
+
+// Setup periodically exports every metric of the gatherer and installs a global tracer
+// provider exporting spans, both over OTLP. The returned function flushes and stops both.
+func Setup(ctx context.Context, cfg *config.OTLPConfig, gatherer prometheus.Gatherer, version string) (func(context.Context) error, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("helm-repo-exporter"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	metricExporter, spanExporter, err := newExporters(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter,
+			sdkmetric.WithInterval(cfg.Interval),
+			sdkmetric.WithProducer(promBridge.NewMetricProducer(promBridge.WithGatherer(gatherer))),
+		)),
+	)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(spanExporter),
+	)
+	otel.SetTracerProvider(tracerProvider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// newExporters creates the metric and span exporters for the configured protocol
+func newExporters(ctx context.Context, cfg *config.OTLPConfig) (sdkmetric.Exporter, sdktrace.SpanExporter, error) {
+	isURL := strings.Contains(cfg.Endpoint, "://")
+
+	if cfg.Protocol == config.OTLPProtocolHTTP {
+		metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithHeaders(cfg.Headers)}
+		traceOpts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		switch {
+		case isURL:
+			metricOpts = append(metricOpts, otlpmetrichttp.WithEndpointURL(cfg.Endpoint))
+			traceOpts = append(traceOpts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		default:
+			metricOpts = append(metricOpts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+			traceOpts = append(traceOpts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+			if cfg.Insecure {
+				metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+				traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+			}
+		}
+		metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+		spanExporter, err := otlptrace.New(ctx, otlptracehttp.NewClient(traceOpts...))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP span exporter: %w", err)
+		}
+		return metricExporter, spanExporter, nil
+	}
+
+	metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithHeaders(cfg.Headers)}
+	traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+	switch {
+	case isURL:
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpointURL(cfg.Endpoint))
+		traceOpts = append(traceOpts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+	default:
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		traceOpts = append(traceOpts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		if cfg.Insecure {
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+			traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+		}
+	}
+	metricExporter, err := otlpmetricgrpc.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+	spanExporter, err := otlptrace.New(ctx, otlptracegrpc.NewClient(traceOpts...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP span exporter: %w", err)
+	}
+	return metricExporter, spanExporter, nil
+}
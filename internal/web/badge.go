@@ -0,0 +1,259 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+// badgeMaxAge is how long clients and proxies may cache a badge
+const badgeMaxAge = 5 * time.Minute
+
+// Badge colors, as used by shields.io
+const (
+	badgeBlue  = "#007ec6"
+	badgeGreen = "#4c1"
+	badgeRed   = "#e05d44"
+	badgeGrey  = "#9f9f9f"
+)
+
+type repositoryStatus struct {
+	analysis    *analyzer.ChartAnalysis
+	lastSuccess time.Time
+	failing     bool
+}
+
+// BadgeHandler serves SVG status badges: /badge/{repository}.svg shows the scrape health
+// of a repository and /badge/{repository}/{chart}.svg the latest version of a chart.
+// The show query parameter selects what chart badges list, from version, age and versions.
+type BadgeHandler struct {
+	mu           sync.RWMutex
+	repositories map[string]*repositoryStatus
+	now          func() time.Time
+}
+
+// NewBadgeHandler creates a badge handler for the configured repositories
+func NewBadgeHandler(repositories []string) *BadgeHandler {
+	h := &BadgeHandler{
+		repositories: make(map[string]*repositoryStatus, len(repositories)),
+		now:          time.Now,
+	}
+	for _, name := range repositories {
+		h.repositories[name] = &repositoryStatus{}
+	}
+	return h
+}
+
+// Update records a successful scrape of a repository
+func (h *BadgeHandler) Update(repository string, analysis *analyzer.ChartAnalysis) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.repositories[repository] = &repositoryStatus{analysis: analysis, lastSuccess: h.now()}
+}
+
+// RecordFailure records a failed scrape of a repository, keeping its previous charts
+func (h *BadgeHandler) RecordFailure(repository string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.repositories[repository]
+	if status == nil {
+		status = &repositoryStatus{}
+		h.repositories[repository] = status
+	}
+	status.failing = true
+}
+
+// ServeHTTP renders the badge named in the path
+func (h *BadgeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/badge/"), ".svg")
+	repository, chart, isChart := strings.Cut(name, "/")
+	if !ok || repository == "" || (isChart && (chart == "" || strings.Contains(chart, "/"))) {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.RLock()
+	status, known := h.repositories[repository]
+	var current repositoryStatus
+	if known {
+		current = *status
+	}
+	h.mu.RUnlock()
+
+	label, message, color := repository, "not found", badgeGrey
+	code := http.StatusNotFound
+	switch {
+	case !known:
+	case isChart:
+		label = chart
+		if current.analysis == nil {
+			message, code = "pending", http.StatusOK
+			break
+		}
+		for i := range current.analysis.ChartsInfo {
+			if info := &current.analysis.ChartsInfo[i]; info.Name == chart {
+				fields := strings.Split(r.URL.Query().Get("show"), ",")
+				if fields[0] == "" {
+					fields = []string{"version", "age"}
+				}
+				var err error
+				message, color, err = chartBadge(info, fields, h.now())
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				code = http.StatusOK
+				break
+			}
+		}
+	default:
+		message, color, code = repositoryBadge(current, h.now()), badgeGreen, http.StatusOK
+		if current.failing {
+			color = badgeRed
+		} else if current.analysis == nil {
+			color = badgeGrey
+		}
+	}
+
+	svg := renderBadge(label, message, color)
+	hash := fnv.New64a()
+	_, _ = hash.Write(svg)
+	etag := fmt.Sprintf(`"%x"`, hash.Sum64())
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(badgeMaxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+	if code == http.StatusOK && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	w.WriteHeader(code)
+	_, _ = w.Write(svg)
+}
+
+// chartBadge describes a chart with the requested fields
+func chartBadge(chart *analyzer.ChartInfo, fields []string, now time.Time) (message, color string, err error) {
+	var latest analyzer.VersionDetail
+	if len(chart.VersionDetails) > 0 {
+		latest = chart.VersionDetails[0] // The first index entry, which Helm treats as the latest
+	}
+
+	var parts []string
+	for _, field := range fields {
+		switch strings.TrimSpace(field) {
+		case "version":
+			if latest.Version != "" {
+				parts = append(parts, "v"+strings.TrimPrefix(latest.Version, "v"))
+			}
+		case "age":
+			if !latest.Created.IsZero() {
+				parts = append(parts, ago(now.Sub(latest.Created)))
+			}
+		case "versions":
+			parts = append(parts, plural(chart.VersionCount, "version"))
+		default:
+			return "", "", fmt.Errorf("unknown badge field %q", field)
+		}
+	}
+
+	color = badgeBlue
+	if chart.Deprecated {
+		parts = append(parts, "deprecated")
+		color = badgeRed
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "unknown")
+	}
+	return strings.Join(parts, ", "), color, nil
+}
+
+// repositoryBadge describes the scrape health of a repository
+func repositoryBadge(status repositoryStatus, now time.Time) string {
+	switch {
+	case status.failing && status.lastSuccess.IsZero():
+		return "failing"
+	case status.failing:
+		return "failing, last success " + ago(now.Sub(status.lastSuccess))
+	case status.analysis == nil:
+		return "pending"
+	}
+	return "healthy, scraped " + ago(now.Sub(status.lastSuccess))
+}
+
+// ago renders a duration as a coarse age such as "3 days ago"
+func ago(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < day:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	case d < 60*day:
+		return plural(int(d/day), "day") + " ago"
+	case d < 365*day:
+		return plural(int(d/(30*day)), "month") + " ago"
+	default:
+		return plural(int(d/(365*day)), "year") + " ago"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// renderBadge draws a flat two-part badge, in the style of shields.io
+func renderBadge(label, message, color string) []byte {
+	labelWidth := textWidth(label) + 10
+	messageWidth := textWidth(message) + 10
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, label, message)
+	b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		labelWidth, labelWidth, messageWidth, color, width)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	for _, text := range []struct {
+		x    float64
+		text string
+	}{{float64(labelWidth) / 2, label}, {float64(labelWidth) + float64(messageWidth)/2, message}} {
+		fmt.Fprintf(&b, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`, text.x, text.text, text.x, text.text)
+	}
+	b.WriteString(`</g></svg>`)
+	return b.Bytes()
+}
+
+// textWidth approximates the width in pixels of text in 11px Verdana
+func textWidth(text string) int {
+	var width float64
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune("il.,:;|!'` ", r):
+			width += 3.9
+		case strings.ContainsRune("fjrt()[]{}/-", r):
+			width += 4.7
+		case strings.ContainsRune("mwMW%", r):
+			width += 10.7
+		case r >= 'A' && r <= 'Z':
+			width += 7.5
+		default:
+			width += 6.9
+		}
+	}
+	return int(width + 0.5)
+}
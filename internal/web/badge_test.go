@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+func TestBadgeHandler(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	h := NewBadgeHandler([]string{"stable"})
+	h.now = func() time.Time { return now }
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	expectBadge := func(path string, code int, message string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := get(path, "")
+		if rec.Code != code || !strings.Contains(rec.Body.String(), "<title>"+message+"</title>") {
+			t.Errorf("%s: expected %d %q, got %d %s", path, code, message, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	expectBadge("/badge/stable.svg", http.StatusOK, "stable: pending")
+	expectBadge("/badge/unknown.svg", http.StatusNotFound, "unknown: not found")
+
+	h.Update("stable", analyzer.AnalyzeChartsWithRepo(&analyzer.HelmIndex{Entries: map[string][]analyzer.ChartVersionInfo{
+		"app": {
+			{Name: "app", Version: "1.4.2", Created: now.Add(-3 * 24 * time.Hour)},
+			{Name: "app", Version: "1.4.1", Created: now.Add(-40 * 24 * time.Hour)},
+		},
+		"old": {{Name: "old", Version: "v0.1.0", Created: now.Add(-400 * 24 * time.Hour), Deprecated: true}},
+	}}, "stable", "https://charts.example.com/index.yaml"))
+	now = now.Add(5 * time.Minute)
+
+	rec := expectBadge("/badge/stable/app.svg", http.StatusOK, "app: v1.4.2, 3 days ago")
+	if rec.Header().Get("Content-Type") != "image/svg+xml; charset=utf-8" || rec.Header().Get("Cache-Control") != "public, max-age=300" {
+		t.Errorf("Unexpected headers %v", rec.Header())
+	}
+	if again := get("/badge/stable/app.svg", rec.Header().Get("ETag")); again.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", again.Code)
+	}
+
+	expectBadge("/badge/stable/app.svg?show=versions", http.StatusOK, "app: 2 versions")
+	expectBadge("/badge/stable/old.svg", http.StatusOK, "old: v0.1.0, 1 year ago, deprecated")
+	expectBadge("/badge/stable/missing.svg", http.StatusNotFound, "missing: not found")
+	expectBadge("/badge/stable.svg", http.StatusOK, "stable: healthy, scraped 5 minutes ago")
+	if rec := get("/badge/stable/app.svg?show=downloads", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown field, got %d", rec.Code)
+	}
+
+	h.RecordFailure("stable")
+	expectBadge("/badge/stable.svg", http.StatusOK, "stable: failing, last success 5 minutes ago")
+	expectBadge("/badge/stable/app.svg", http.StatusOK, "app: v1.4.2, 3 days ago")
+}
+
+func TestAgo(t *testing.T) {
+	for d, expected := range map[time.Duration]string{
+		30 * time.Second:     "just now",
+		time.Minute:          "1 minute ago",
+		5 * time.Hour:        "5 hours ago",
+		45 * 24 * time.Hour:  "45 days ago",
+		90 * 24 * time.Hour:  "3 months ago",
+		800 * 24 * time.Hour: "2 years ago",
+	} {
+		if got := ago(d); got != expected {
+			t.Errorf("ago(%v) = %q, expected %q", d, got, expected)
+		}
+	}
+}
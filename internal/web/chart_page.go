@@ -0,0 +1,331 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+// maxTimelineMonths caps the number of months shown in a chart's release timeline
+const maxTimelineMonths = 36
+
+// Download size lookups of a chart page
+const (
+	sizeLookupTimeout = 5 * time.Second
+	sizeLookupWorkers = 4
+	sizeFailureTTL    = 5 * time.Minute // Failed or timed out lookups are not retried before it passes
+)
+
+// SizeFunc returns the size in bytes of a chart tarball of a repository
+type SizeFunc func(ctx context.Context, repository, chartURL string) (int64, error)
+
+// versionRow is a row of a chart page's version table
+type versionRow struct {
+	analyzer.VersionDetail
+	Size int64 // 0 when unknown
+}
+
+// timelineMonth is a bar of the release timeline
+type timelineMonth struct {
+	Month   string
+	Count   int
+	Percent int // Height relative to the busiest month
+}
+
+// serveChart renders the detail page of a chart
+func (h *HTMLGenerator) serveChart(w http.ResponseWriter, r *http.Request, analysis *analyzer.ChartAnalysis, repository, chart string) {
+	var info *analyzer.ChartInfo
+	for i := range analysis.ChartsInfo {
+		if c := &analysis.ChartsInfo[i]; c.Repository == repository && c.Name == chart {
+			info = c
+			break
+		}
+	}
+	if info == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var latest analyzer.VersionDetail
+	if len(info.VersionDetails) > 0 {
+		latest = info.VersionDetails[0]
+	}
+	repoURL := publicURL(info.RepositoryURL)
+
+	data := struct {
+		BasePath  string
+		Chart     *analyzer.ChartInfo
+		Latest    analyzer.VersionDetail
+		RepoAdd   string
+		Install   string
+		Timeline  []timelineMonth
+		Versions  []versionRow
+		Generated time.Time
+	}{
+		BasePath:  h.basePath,
+		Chart:     info,
+		Latest:    latest,
+		RepoAdd:   "helm repo add " + info.Repository + " " + repoURL,
+		Install:   "helm install " + info.Name + " " + info.Repository + "/" + info.Name,
+		Timeline:  releaseTimeline(info.VersionDetails),
+		Versions:  h.versionRows(r.Context(), info),
+		Generated: time.Now(),
+	}
+	if latest.Version != "" {
+		data.Install += " --version " + latest.Version
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.template.ExecuteTemplate(w, "chart", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// SetSizeFunc sets how chart pages look up download sizes, which Helm indexes don't record.
+// Without it, sizes are not shown.
+func (h *HTMLGenerator) SetSizeFunc(f SizeFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sizeFunc = f
+}
+
+// versionRows returns the versions of a chart with their download sizes.
+// Sizes are cached by URL and digest, and looked up concurrently within sizeLookupTimeout.
+// Failed lookups are cached for sizeFailureTTL, so that page views don't repeat them.
+func (h *HTMLGenerator) versionRows(ctx context.Context, info *analyzer.ChartInfo) []versionRow {
+	rows := make([]versionRow, len(info.VersionDetails))
+	var missing []int
+	h.sizesMu.Lock()
+	for i, detail := range info.VersionDetails {
+		rows[i].VersionDetail = detail
+		if size, ok := h.sizes[sizeKey(detail)]; ok {
+			rows[i].Size = size
+		} else if failedAt, ok := h.sizeFailures[sizeKey(detail)]; ok && time.Since(failedAt) < sizeFailureTTL {
+			continue
+		} else if detail.URL != "" {
+			missing = append(missing, i)
+		}
+	}
+	h.sizesMu.Unlock()
+
+	h.mu.RLock()
+	sizeFunc := h.sizeFunc
+	h.mu.RUnlock()
+	if sizeFunc == nil || len(missing) == 0 {
+		return rows
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sizeLookupTimeout)
+	defer cancel()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(sizeLookupWorkers, len(missing)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				size, err := sizeFunc(ctx, info.Repository, rows[i].URL)
+				key := sizeKey(rows[i].VersionDetail)
+				h.sizesMu.Lock()
+				if err != nil {
+					h.sizeFailures[key] = time.Now()
+				} else {
+					rows[i].Size = size
+					h.sizes[key] = size
+					delete(h.sizeFailures, key)
+				}
+				h.sizesMu.Unlock()
+			}
+		}()
+	}
+	for _, i := range missing {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return rows
+}
+
+func sizeKey(detail analyzer.VersionDetail) string {
+	return detail.URL + " " + detail.Digest
+}
+
+// releaseTimeline counts the versions released per month, from the first release or
+// the last maxTimelineMonths months to the latest release
+func releaseTimeline(versions []analyzer.VersionDetail) []timelineMonth {
+	var first, last time.Time
+	counts := make(map[time.Time]int)
+	for _, v := range versions {
+		if v.Created.IsZero() {
+			continue
+		}
+		created := v.Created.UTC()
+		month := time.Date(created.Year(), created.Month(), 1, 0, 0, 0, 0, time.UTC)
+		counts[month]++
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+		if month.After(last) {
+			last = month
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	if earliest := last.AddDate(0, 1-maxTimelineMonths, 0); first.Before(earliest) {
+		first = earliest
+	}
+
+	var timeline []timelineMonth
+	busiest := 0
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		timeline = append(timeline, timelineMonth{Month: month.Format("2006-01"), Count: counts[month]})
+		busiest = max(busiest, counts[month])
+	}
+	for i := range timeline {
+		timeline[i].Percent = timeline[i].Count * 100 / busiest
+	}
+	return timeline
+}
+
+// publicURL removes credentials from a URL, so that it can be shown
+func publicURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+const chartPageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Chart.Name}} - Helm Repository Dashboard</title>
+    {{template "style"}}
+</head>
+<body>
+    <div class="container">
+        <a class="back-link" href="{{.BasePath}}">← All charts</a>
+
+        <div class="header">
+            <div class="chart-title-section">
+                {{if .Chart.Icon}}
+                <img src="{{safeIconURL .Chart.Icon}}" alt="{{.Chart.Name}}" class="chart-icon" onerror="this.style.display='none'">
+                {{end}}
+                <div>
+                    <h1>{{.Chart.Name}}{{if .Chart.Deprecated}}<span class="deprecated-tag">Deprecated</span>{{end}}</h1>
+                    <div style="font-size: 12px; color: #667eea; font-weight: 600;">📁 {{.Chart.Repository}}</div>
+                </div>
+            </div>
+            {{if .Chart.Description}}
+            <p class="chart-description" style="margin-top: 10px;">{{.Chart.Description}}</p>
+            {{end}}
+            <p class="subtitle">Generated: {{.Generated.Format "2006-01-02 15:04:05 MST"}}</p>
+        </div>
+
+        <div class="detail-section">
+            <h2>Install</h2>
+            <div class="snippet"><code>{{.RepoAdd}}</code><button class="copy-button" onclick="copySnippet(this)">Copy</button></div>
+            <div class="snippet"><code>{{.Install}}</code><button class="copy-button" onclick="copySnippet(this)">Copy</button></div>
+        </div>
+
+        <div class="detail-section">
+            <h2>Metadata</h2>
+            <dl class="detail-list">
+                {{if .Latest.Version}}<dt>Latest version</dt><dd>{{.Latest.Version}}</dd>{{end}}
+                {{if .Chart.AppVersion}}<dt>App version</dt><dd>{{.Chart.AppVersion}}</dd>{{end}}
+                {{if .Chart.Type}}<dt>Type</dt><dd>{{.Chart.Type}}</dd>{{end}}
+                <dt>Versions</dt><dd>{{.Chart.VersionCount}}</dd>
+                {{if not .Chart.OldestVersion.IsZero}}<dt>First release</dt><dd>{{.Chart.OldestVersion.Format "2006-01-02"}}</dd>{{end}}
+                {{if not .Chart.NewestVersion.IsZero}}<dt>Latest release</dt><dd>{{.Chart.NewestVersion.Format "2006-01-02"}}</dd>{{end}}
+                {{if .Chart.Home}}<dt>Home</dt><dd><a href="{{.Chart.Home}}" rel="noopener noreferrer">{{.Chart.Home}}</a></dd>{{end}}
+                {{if .Chart.Sources}}<dt>Sources</dt><dd>{{range .Chart.Sources}}<a href="{{.}}" rel="noopener noreferrer">{{.}}</a><br>{{end}}</dd>{{end}}
+                {{if .Chart.Keywords}}<dt>Keywords</dt><dd>{{range .Chart.Keywords}}<span class="keyword">{{.}}</span>{{end}}</dd>{{end}}
+                {{if .Chart.ProvenanceChecked}}<dt>Signatures</dt><dd>{{.Chart.SignedVersions}} signed, {{.Chart.UnsignedVersions}} unsigned, {{.Chart.InvalidVersions}} invalid</dd>{{end}}
+            </dl>
+        </div>
+
+        {{if .Chart.Maintainers}}
+        <div class="detail-section">
+            <h2>Maintainers</h2>
+            <ul style="list-style: none; font-size: 14px; color: #4a5568;">
+                {{range .Chart.Maintainers}}
+                <li>{{if .URL}}<a href="{{.URL}}" rel="noopener noreferrer">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{if .Email}} &lt;<a href="mailto:{{.Email}}">{{.Email}}</a>&gt;{{end}}</li>
+                {{end}}
+            </ul>
+        </div>
+        {{end}}
+
+        {{if .Chart.Dependencies}}
+        <div class="detail-section">
+            <h2>Dependencies</h2>
+            <table class="lint-table">
+                <thead>
+                    <tr><th>Name</th><th>Version</th><th>Repository</th></tr>
+                </thead>
+                <tbody>
+                    {{range .Chart.Dependencies}}
+                    <tr><td>{{.Name}}</td><td>{{.Version}}</td><td>{{.Repository}}</td></tr>
+                    {{end}}
+                </tbody>
+            </table>
+        </div>
+        {{end}}
+
+        {{if .Timeline}}
+        <div class="detail-section">
+            <h2>Release Timeline</h2>
+            <div class="timeline">
+                {{range .Timeline}}
+                <div class="timeline-bar{{if not .Count}} empty{{end}}" style="height: {{.Percent}}%" title="{{.Month}}: {{.Count}} release(s)"></div>
+                {{end}}
+            </div>
+            <div class="timeline-labels">
+                <span>{{(index .Timeline 0).Month}}</span>
+                <span>{{.Chart.NewestVersion.UTC.Format "2006-01"}}</span>
+            </div>
+        </div>
+        {{end}}
+
+        <div class="detail-section">
+            <h2>Versions</h2>
+            <table class="lint-table">
+                <thead>
+                    <tr><th>Version</th><th>App Version</th><th>Released</th><th>Signature</th><th>Digest</th><th>Size</th><th></th></tr>
+                </thead>
+                <tbody>
+                    {{range .Versions}}
+                    <tr>
+                        <td class="version-number">{{.Version}}</td>
+                        <td>{{.AppVersion}}</td>
+                        <td>{{if not .Created.IsZero}}{{.Created.Format "2006-01-02"}}{{end}}</td>
+                        <td>{{if .Signature}}<span class="sig-badge sig-{{.Signature}}">{{.Signature}}</span>{{end}}</td>
+                        <td title="{{.Digest}}"><code>{{shortDigest .Digest}}</code></td>
+                        <td>{{if .Size}}{{bytes .Size}}{{end}}</td>
+                        <td>{{if .URL}}<a href="{{publicURL .URL}}" class="version-link" rel="noopener noreferrer">⬇ Download</a>{{end}}</td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+        </div>
+    </div>
+
+    <script>
+        function copySnippet(button) {
+            const text = button.previousElementSibling.textContent;
+            navigator.clipboard.writeText(text).then(() => {
+                button.textContent = 'Copied';
+                setTimeout(() => { button.textContent = 'Copy'; }, 1500);
+            });
+        }
+    </script>
+</body>
+</html>
+`
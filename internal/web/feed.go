@@ -0,0 +1,168 @@
+package web
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+// maxFeedEntries caps the number of versions listed in a feed
+const maxFeedEntries = 100
+
+// FeedHandler serves Atom feeds of recently published chart versions:
+// /feeds.atom for every repository and /feeds/{repository}.atom for one.
+// The chart query parameter, which may be repeated, restricts a feed to some charts.
+type FeedHandler struct {
+	mu       sync.RWMutex
+	analyses map[string]*analyzer.ChartAnalysis // Latest analysis per repository
+}
+
+// NewFeedHandler creates a new feed handler
+func NewFeedHandler() *FeedHandler {
+	return &FeedHandler{analyses: make(map[string]*analyzer.ChartAnalysis)}
+}
+
+// Update replaces the analysis of a repository
+func (h *FeedHandler) Update(repository string, analysis *analyzer.ChartAnalysis) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.analyses[repository] = analysis
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Updated time.Time `xml:"updated"`
+	Link    *atomLink `xml:"link,omitempty"`
+	Summary string    `xml:"summary,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated time.Time   `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// ServeHTTP renders the feed named in the path
+func (h *FeedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	repository := ""
+	if r.URL.Path != "/feeds.atom" {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/feeds/"), ".atom")
+		if !ok || name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		repository = name
+	}
+
+	h.mu.RLock()
+	var analyses []*analyzer.ChartAnalysis
+	if repository != "" {
+		analysis, ok := h.analyses[repository]
+		if !ok {
+			h.mu.RUnlock()
+			http.NotFound(w, r)
+			return
+		}
+		analyses = append(analyses, analysis)
+	} else {
+		for _, analysis := range h.analyses {
+			analyses = append(analyses, analysis)
+		}
+	}
+	h.mu.RUnlock()
+
+	if repository == "" && len(analyses) == 0 {
+		http.Error(w, "No data available yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	feed := buildFeed(analyses, repository, r.URL.Query()["chart"])
+	feed.Link = atomLink{Href: r.URL.RequestURI(), Rel: "self"}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(feed); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// buildFeed lists the newest versions of the analyses, restricted to charts when given.
+// Versions without a creation date are left out, as they cannot be placed in time.
+func buildFeed(analyses []*analyzer.ChartAnalysis, repository string, charts []string) *atomFeed {
+	wanted := make(map[string]bool, len(charts))
+	for _, chart := range charts {
+		wanted[chart] = true
+	}
+
+	feed := &atomFeed{
+		ID:     "urn:helm-repo-exporter:feed",
+		Title:  "New Helm chart versions",
+		Author: "helm-repo-exporter",
+	}
+	if repository != "" {
+		feed.ID += ":" + repository
+		feed.Title += " in " + repository
+	}
+
+	for _, analysis := range analyses {
+		for _, chart := range analysis.ChartsInfo {
+			if len(wanted) > 0 && !wanted[chart.Name] {
+				continue
+			}
+			for _, detail := range chart.VersionDetails {
+				if detail.Created.IsZero() {
+					continue
+				}
+				entry := atomEntry{
+					ID:      "urn:helm-repo-exporter:" + chart.Repository + ":" + chart.Name + ":" + detail.Version,
+					Title:   chart.Repository + "/" + chart.Name + " " + detail.Version,
+					Updated: detail.Created.UTC(),
+					Summary: chart.Description,
+				}
+				if link := feedLink(detail.URL); link != "" {
+					entry.Link = &atomLink{Href: link}
+				}
+				feed.Entries = append(feed.Entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(feed.Entries, func(i, j int) bool {
+		if !feed.Entries[i].Updated.Equal(feed.Entries[j].Updated) {
+			return feed.Entries[i].Updated.After(feed.Entries[j].Updated)
+		}
+		return feed.Entries[i].ID < feed.Entries[j].ID
+	})
+	feed.Entries = feed.Entries[:min(len(feed.Entries), maxFeedEntries)]
+	feed.Updated = time.Now().UTC()
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	}
+	return feed
+}
+
+// feedLink returns a chart URL without credentials, or "" if it is not http(s)
+func feedLink(chartURL string) string {
+	u, err := url.Parse(chartURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	u.User = nil
+	return u.String()
+}
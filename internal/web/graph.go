@@ -0,0 +1,61 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+// DependencyGraphHandler serves the chart dependency graph as JSON or Graphviz DOT
+type DependencyGraphHandler struct {
+	mu    sync.RWMutex
+	graph *analyzer.DependencyGraph
+}
+
+// NewDependencyGraphHandler creates a new dependency graph handler
+func NewDependencyGraphHandler() *DependencyGraphHandler {
+	return &DependencyGraphHandler{}
+}
+
+// Update replaces the served graph
+func (h *DependencyGraphHandler) Update(graph *analyzer.DependencyGraph) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.graph = graph
+}
+
+// ServeHTTP renders the graph. The format is chosen by the path suffix (.json or .dot)
+// or the format query parameter, defaulting to JSON.
+func (h *DependencyGraphHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	graph := h.graph
+	h.mu.RUnlock()
+
+	if graph == nil {
+		http.Error(w, "No data available yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.HasSuffix(r.URL.Path, ".dot") {
+		format = "dot"
+	}
+
+	switch format {
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		if err := graph.WriteDOT(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(graph); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	default:
+		http.Error(w, "unsupported format: "+format, http.StatusBadRequest)
+	}
+}
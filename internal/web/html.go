@@ -1,22 +1,36 @@
 package web
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/logging"
 )
 
+// maxLintRows caps the number of lint violations rendered on the dashboard
+const maxLintRows = 200
+
 // HTMLGenerator generates HTML dashboard for charts
 type HTMLGenerator struct {
+	basePath     string // Path of the dashboard, under which chart pages are served
 	mu           sync.RWMutex
 	analysis     *analyzer.ChartAnalysis
 	repoAnalyses map[string]*analyzer.ChartAnalysis // Per-repository analysis cache
+	repositories map[string]*repositoryRow          // Repository overview, by name
+	redactor     *logging.Redactor                  // Removes credentials from scrape errors
 	template     *template.Template
+
+	sizeFunc     SizeFunc
+	sizesMu      sync.Mutex
+	sizes        map[string]int64     // Chart tarball sizes, by URL and digest
+	sizeFailures map[string]time.Time // Times of failed size lookups, by URL and digest
 }
 
 // sanitizeIconURL validates and sanitizes icon URLs to prevent XSS attacks
@@ -80,8 +94,8 @@ func sanitizeIconURL(iconURL string) string {
 	return iconURL
 }
 
-// NewHTMLGenerator creates a new HTML generator
-func NewHTMLGenerator() (*HTMLGenerator, error) {
+// NewHTMLGenerator creates a new HTML generator for a dashboard served at basePath
+func NewHTMLGenerator(basePath string) (*HTMLGenerator, error) {
 	// Create template with custom functions
 	funcMap := template.FuncMap{
 		"safeIconURL": func(iconURL string) template.URL {
@@ -92,111 +106,63 @@ func NewHTMLGenerator() (*HTMLGenerator, error) {
 			// #nosec G203 -- sanitizeIconURL validates the URL before returning it
 			return template.URL(sanitized)
 		},
+		"publicURL": publicURL,
+		"bytes":     formatBytes,
+		"ago": func(t time.Time) string {
+			return ago(time.Since(t))
+		},
+		"shortDigest": func(digest string) string {
+			return digest[:min(len(digest), 12)]
+		},
 	}
 
 	tmpl, err := template.New("charts").Funcs(funcMap).Parse(htmlTemplate)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := tmpl.Parse(styleTemplate); err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.New("chart").Parse(chartPageTemplate); err != nil {
+		return nil, err
+	}
 
 	return &HTMLGenerator{
+		basePath:     strings.TrimSuffix(basePath, "/"),
 		template:     tmpl,
 		repoAnalyses: make(map[string]*analyzer.ChartAnalysis),
+		repositories: make(map[string]*repositoryRow),
+		sizes:        make(map[string]int64),
+		sizeFailures: make(map[string]time.Time),
 	}, nil
 }
 
-// Update updates the analysis data
-// If the analysis has a single repository, it updates that repo's data and merges all repos
-// If the analysis has multiple repositories (merged), it replaces the entire view
-func (h *HTMLGenerator) Update(analysis *analyzer.ChartAnalysis) {
+// UpdateRepository replaces the analysis of a repository and rebuilds the combined view
+func (h *HTMLGenerator) UpdateRepository(repository string, analysis *analyzer.ChartAnalysis) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	// Check if this is a single-repo update or a merged update
-	if len(analysis.ChartsInfo) > 0 {
-		// Determine if this is a single repository or merged data
-		// Single repo: all charts have the same repository name
-		// Merged: charts may have different repository names
-		firstRepo := analysis.ChartsInfo[0].Repository
-		isSingleRepo := true
-		for _, chart := range analysis.ChartsInfo {
-			if chart.Repository != firstRepo {
-				isSingleRepo = false
-				break
-			}
-		}
-
-		if isSingleRepo && firstRepo != "" {
-			// Update the specific repository's data
-			h.repoAnalyses[firstRepo] = analysis
-
-			// Merge all repository analyses
-			h.analysis = h.mergeAllRepos()
-		} else {
-			// This is already merged data (from initial scrape or full update)
-			h.analysis = analysis
-
-			// Note: Per-repo cache update is skipped for merged data
-			// Individual repo data will be properly updated on next scrape
-		}
-	} else {
-		// Empty analysis, just set it
-		h.analysis = analysis
-	}
+	h.repoAnalyses[repository] = analysis
+	h.analysis = h.mergeAllRepos()
 }
 
-// mergeAllRepos merges all cached repository analyses into a single view
+// mergeAllRepos merges all cached repository analyses into a single view, in repository name order
 func (h *HTMLGenerator) mergeAllRepos() *analyzer.ChartAnalysis {
-	if len(h.repoAnalyses) == 0 {
-		return &analyzer.ChartAnalysis{}
+	names := make([]string, 0, len(h.repoAnalyses))
+	for name := range h.repoAnalyses {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	var merged *analyzer.ChartAnalysis
-	for _, repoAnalysis := range h.repoAnalyses {
-		if merged == nil {
-			// Deep copy the first analysis
-			merged = &analyzer.ChartAnalysis{
-				TotalCharts:     repoAnalysis.TotalCharts,
-				TotalVersions:   repoAnalysis.TotalVersions,
-				ChartsInfo:      append([]analyzer.ChartInfo{}, repoAnalysis.ChartsInfo...),
-				OldestChartDate: repoAnalysis.OldestChartDate,
-				NewestChartDate: repoAnalysis.NewestChartDate,
-				MedianChartDate: repoAnalysis.MedianChartDate,
-			}
-		} else {
-			// Merge this repo's data
-			merged.TotalCharts += repoAnalysis.TotalCharts
-			merged.TotalVersions += repoAnalysis.TotalVersions
-			merged.ChartsInfo = append(merged.ChartsInfo, repoAnalysis.ChartsInfo...)
-
-			// Update date statistics
-			if !repoAnalysis.OldestChartDate.IsZero() {
-				if merged.OldestChartDate.IsZero() || repoAnalysis.OldestChartDate.Before(merged.OldestChartDate) {
-					merged.OldestChartDate = repoAnalysis.OldestChartDate
-				}
-			}
-
-			if !repoAnalysis.NewestChartDate.IsZero() {
-				if merged.NewestChartDate.IsZero() || repoAnalysis.NewestChartDate.After(merged.NewestChartDate) {
-					merged.NewestChartDate = repoAnalysis.NewestChartDate
-				}
-			}
-
-			// For median, approximate by averaging (good enough for display)
-			if !repoAnalysis.MedianChartDate.IsZero() && !merged.MedianChartDate.IsZero() {
-				avg := (merged.MedianChartDate.Unix() + repoAnalysis.MedianChartDate.Unix()) / 2
-				merged.MedianChartDate = time.Unix(avg, 0)
-			} else if !repoAnalysis.MedianChartDate.IsZero() {
-				merged.MedianChartDate = repoAnalysis.MedianChartDate
-			}
-		}
+	analyses := make([]*analyzer.ChartAnalysis, 0, len(names))
+	for _, name := range names {
+		analyses = append(analyses, h.repoAnalyses[name])
 	}
-
-	return merged
+	return analyzer.MergeAnalyses(analyses...)
 }
 
-// ServeHTTP handles HTTP requests for the charts dashboard
-func (h *HTMLGenerator) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+// ServeHTTP handles HTTP requests for the charts dashboard, and below it for
+// repository views ({basePath}/{repository}) and chart pages ({basePath}/{repository}/{chart})
+func (h *HTMLGenerator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.mu.RLock()
 	analysis := h.analysis
 	h.mu.RUnlock()
@@ -206,12 +172,88 @@ func (h *HTMLGenerator) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
 		return
 	}
 
+	rows := h.overviewRows()
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, h.basePath), "/")
+	scope, chart, isChart := strings.Cut(rest, "/")
+	switch {
+	case isChart && !strings.Contains(chart, "/"):
+		h.serveChart(w, r, analysis, scope, chart)
+		return
+	case isChart:
+		http.NotFound(w, r)
+		return
+	case scope != "":
+		// The repository view shows the repository's own analysis rather than the merged one
+		var row *repositoryRow
+		for _, candidate := range rows {
+			if candidate.Name == scope {
+				row = candidate
+			}
+		}
+		if row == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if row.analysis == nil {
+			http.Error(w, "No data available yet", http.StatusServiceUnavailable)
+			return
+		}
+		analysis, rows = row.analysis, []*repositoryRow{row}
+	}
+
+	query, err := parseDashboardQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if scope != "" {
+		query.Repository = ""
+	}
+	charts, matching := query.apply(analysis.ChartsInfo)
+	pages := (matching + query.Limit - 1) / query.Limit
+	first := (query.Page - 1) * query.Limit
+
+	lintViolations := sortLintViolations(analysis.LintViolations)
+
 	data := struct {
-		Analysis  *analyzer.ChartAnalysis
-		Generated time.Time
+		BasePath       string
+		Scope          string // Repository of a repository view
+		Analysis       *analyzer.ChartAnalysis
+		Overview       []*repositoryRow
+		Query          dashboardQuery
+		DefaultLimit   int
+		Repositories   []string
+		Charts         []analyzer.ChartInfo
+		Matching       int
+		First, Last    int // 1-based positions of the listed charts among the matching ones
+		Pages          int
+		PrevURL        string
+		NextURL        string
+		Generated      time.Time
+		LintViolations []analyzer.LintViolation
+		LintTotal      int
 	}{
-		Analysis:  analysis,
-		Generated: time.Now(),
+		BasePath:       h.basePath,
+		Scope:          scope,
+		Analysis:       analysis,
+		Overview:       rows,
+		Query:          query,
+		DefaultLimit:   defaultPageLimit,
+		Repositories:   repositoryNames(analysis.ChartsInfo),
+		Charts:         charts,
+		Matching:       matching,
+		First:          first + 1,
+		Last:           first + len(charts),
+		Pages:          pages,
+		Generated:      time.Now(),
+		LintViolations: lintViolations[:min(len(lintViolations), maxLintRows)],
+		LintTotal:      len(lintViolations),
+	}
+	if query.Page > 1 {
+		data.PrevURL = query.url(min(query.Page-1, max(pages, 1)))
+	}
+	if query.Page < pages {
+		data.NextURL = query.url(query.Page + 1)
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -220,12 +262,312 @@ func (h *HTMLGenerator) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// formatBytes renders a size in bytes with a binary unit, such as "1.5 MiB"
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// repositoryNames returns the sorted names of the repositories of the charts
+func repositoryNames(charts []analyzer.ChartInfo) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, chart := range charts {
+		if chart.Repository != "" && !seen[chart.Repository] {
+			seen[chart.Repository] = true
+			names = append(names, chart.Repository)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortLintViolations returns a copy of the violations ordered by severity, then repository and chart
+func sortLintViolations(violations []analyzer.LintViolation) []analyzer.LintViolation {
+	rank := map[analyzer.LintSeverity]int{analyzer.LintError: 0, analyzer.LintWarning: 1, analyzer.LintInfo: 2}
+
+	sorted := append([]analyzer.LintViolation{}, violations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if rank[sorted[i].Severity] != rank[sorted[j].Severity] {
+			return rank[sorted[i].Severity] < rank[sorted[j].Severity]
+		}
+		if sorted[i].Repository != sorted[j].Repository {
+			return sorted[i].Repository < sorted[j].Repository
+		}
+		return sorted[i].Chart < sorted[j].Chart
+	})
+	return sorted
+}
+
 const htmlTemplate = `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>Helm Repository Dashboard</title>
+    {{template "style"}}
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            {{if .Scope}}
+            <a class="back-link" style="color: #667eea; margin-bottom: 10px;" href="{{.BasePath}}">← All repositories</a>
+            <h1>📁 {{.Scope}}</h1>
+            {{else}}
+            <h1>🚀 Helm Repository Dashboard</h1>
+            {{end}}
+            <p class="subtitle">Generated: {{.Generated.Format "2006-01-02 15:04:05 MST"}}</p>
+        </div>
+
+        <div class="stats">
+            <div class="stat-card">
+                <div class="stat-value">{{.Analysis.TotalCharts}}</div>
+                <div class="stat-label">Total Charts</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-value">{{.Analysis.TotalVersions}}</div>
+                <div class="stat-label">Total Versions</div>
+            </div>
+            {{if .Analysis.DeprecatedCharts}}
+            <div class="stat-card">
+                <div class="stat-value">{{.Analysis.DeprecatedCharts}}</div>
+                <div class="stat-label">Deprecated Charts</div>
+            </div>
+            {{end}}
+            {{if not .Analysis.OldestChartDate.IsZero}}
+            <div class="stat-card">
+                <div class="stat-value">{{.Analysis.OldestChartDate.Format "2006-01-02"}}</div>
+                <div class="stat-label">Oldest Chart</div>
+            </div>
+            {{end}}
+            {{if not .Analysis.NewestChartDate.IsZero}}
+            <div class="stat-card">
+                <div class="stat-value">{{.Analysis.NewestChartDate.Format "2006-01-02"}}</div>
+                <div class="stat-label">Newest Chart</div>
+            </div>
+            {{end}}
+            {{if .Analysis.ReleaseDates}}
+            <div class="stat-card">
+                <div class="stat-value">{{(.Analysis.Quantile 0.5).Format "2006-01-02"}}</div>
+                <div class="stat-label">Median Release</div>
+                <div class="stat-label" title="10th and 90th percentile of release dates">p10 {{(.Analysis.Quantile 0.1).Format "2006-01-02"}} · p90 {{(.Analysis.Quantile 0.9).Format "2006-01-02"}}</div>
+            </div>
+            {{end}}
+        </div>
+
+        {{if .Overview}}
+        <div class="detail-section">
+            <h2>Repositories</h2>
+            <table class="lint-table">
+                <thead>
+                    <tr><th>Repository</th><th>Health</th><th>URL</th><th>Auth</th><th>Interval</th><th>Last Success</th><th>Last Error</th><th>Charts</th><th>Versions</th><th>Index Size</th></tr>
+                </thead>
+                <tbody>
+                    {{range .Overview}}
+                    {{$health := .Health $.Generated}}
+                    <tr>
+                        <td><a href="{{$.BasePath}}/{{.Name}}">{{.Name}}</a></td>
+                        <td><span class="health health-{{$health}}">{{$health}}</span></td>
+                        <td style="word-break: break-all;">{{.URL}}</td>
+                        <td>{{.Auth}}</td>
+                        <td>{{if .ScanInterval}}{{.ScanInterval}}{{end}}</td>
+                        <td>{{if not .LastSuccess.IsZero}}<span title="{{.LastSuccess.Format "2006-01-02 15:04:05 MST"}}">{{ago .LastSuccess}}</span>{{else}}never{{end}}</td>
+                        <td>{{if .LastError}}<span title="{{.LastErrorAt.Format "2006-01-02 15:04:05 MST"}}">{{ago .LastErrorAt}}: {{.LastError}}</span>{{end}}</td>
+                        <td>{{.Charts}}</td>
+                        <td>{{.Versions}}</td>
+                        <td>{{if .IndexSize}}{{bytes .IndexSize}}{{else if not .LastSuccess.IsZero}}generated{{end}}</td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+        </div>
+        {{end}}
+
+        {{if .LintTotal}}
+        <details class="lint-container">
+            <summary>🧹 {{.LintTotal}} index lint violation(s)</summary>
+            <table class="lint-table">
+                <thead>
+                    <tr><th>Severity</th><th>Repository</th><th>Rule</th><th>Chart</th><th>Version</th><th>Message</th></tr>
+                </thead>
+                <tbody>
+                    {{range .LintViolations}}
+                    <tr>
+                        <td><span class="severity severity-{{.Severity}}">{{.Severity}}</span></td>
+                        <td>{{.Repository}}</td>
+                        <td>{{.Rule}}</td>
+                        <td>{{.Chart}}</td>
+                        <td>{{.Version}}</td>
+                        <td>{{.Message}}</td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+            {{if gt .LintTotal (len .LintViolations)}}
+            <p class="subtitle" style="margin-top: 10px;">Showing the first {{len .LintViolations}} violations; see the helm_repo_lint_violations metric for totals.</p>
+            {{end}}
+        </details>
+        {{end}}
+
+        <form class="filters" method="get">
+            {{if not .Scope}}
+            <div class="filter-group">
+                <label class="filter-label" for="repoFilter">Filter by Repository</label>
+                <select id="repoFilter" name="repository" class="filter-input" onchange="this.form.submit()">
+                    <option value="">All Repositories</option>
+                    {{range .Repositories}}
+                    <option value="{{.}}"{{if eq . $.Query.Repository}} selected{{end}}>{{.}}</option>
+                    {{end}}
+                </select>
+            </div>
+            {{end}}
+            <div class="filter-group">
+                <label class="filter-label" for="chartFilter">Search Charts</label>
+                <input type="search" id="chartFilter" name="q" value="{{.Query.Search}}" class="filter-input" placeholder="Name, description or keyword, then Enter">
+            </div>
+            <div class="filter-group" style="flex: 0; min-width: 180px;">
+                <label class="filter-label" for="sortOrder">Sort by</label>
+                <select id="sortOrder" name="sort" class="filter-input" onchange="this.form.submit()">
+                    <option value="name"{{if eq .Query.Sort "name"}} selected{{end}}>Name</option>
+                    <option value="age"{{if eq .Query.Sort "age"}} selected{{end}}>Latest release</option>
+                    <option value="versions"{{if eq .Query.Sort "versions"}} selected{{end}}>Version count</option>
+                </select>
+            </div>
+            <label class="filter-toggle" for="deprecatedFilter">
+                <input type="checkbox" id="deprecatedFilter" name="hideDeprecated" value="1"{{if .Query.HideDeprecated}} checked{{end}} onchange="this.form.submit()">
+                Hide deprecated
+            </label>
+            {{if ne .Query.Limit .DefaultLimit}}<input type="hidden" name="limit" value="{{.Query.Limit}}">{{end}}
+            <div class="filter-stats" id="filterStats">
+                {{if .Charts}}Showing {{.First}}–{{.Last}} of {{.Matching}} matching charts{{else}}No matching charts{{end}} ({{.Analysis.TotalCharts}} in total)
+            </div>
+        </form>
+
+        <div class="charts-container">
+            <h2 style="margin-bottom: 20px; color: #2d3748;">📦 Available Charts</h2>
+            {{if .Charts}}
+            <div class="charts-grid" id="chartsGrid">
+                {{range .Charts}}
+                <div class="chart-item{{if .Deprecated}} deprecated{{end}}" data-chart-name="{{.Name}}" data-repository="{{.Repository}}" data-deprecated="{{.Deprecated}}">
+                    <div class="chart-header">
+                        <div class="chart-title-section">
+                            {{if .Icon}}
+                            <img src="{{safeIconURL .Icon}}" alt="{{.Name}}" class="chart-icon" onerror="this.style.display='none'">
+                            {{end}}
+                            <div>
+                                <div class="chart-name">{{if .Repository}}<a class="chart-link" href="{{$.BasePath}}/{{.Repository}}/{{.Name}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{if .Deprecated}}<span class="deprecated-tag">Deprecated</span>{{end}}</div>
+                                {{if .Repository}}
+                                <div style="font-size: 12px; color: #667eea; font-weight: 600; margin-top: 2px;">📁 {{.Repository}}</div>
+                                {{end}}
+                            </div>
+                        </div>
+                        {{if .ProvenanceChecked}}
+                        {{$sig := .SignatureSummary}}
+                        <span class="sig-badge sig-{{$sig}}" title="{{.SignedVersions}} signed, {{.UnsignedVersions}} unsigned, {{.InvalidVersions}} invalid">
+                            {{if eq $sig "signed"}}🔒 Signed{{else if eq $sig "invalid"}}⚠️ Invalid signature{{else}}🔓 Unsigned{{end}}
+                        </span>
+                        {{end}}
+                    </div>
+                    {{if .Description}}
+                    <div class="chart-description">{{.Description}}</div>
+                    {{end}}
+                    <div class="chart-meta">
+                        <div class="meta-item">
+                            <span class="badge" onclick="toggleVersions(this)">
+                                {{.VersionCount}} versions
+                                <span class="expand-icon">▼</span>
+                            </span>
+                        </div>
+                        {{if not .OldestVersion.IsZero}}
+                        <div class="meta-item">
+                            <span class="meta-label">Oldest:</span>
+                            <span class="date">{{.OldestVersion.Format "2006-01-02"}}</span>
+                        </div>
+                        {{end}}
+                        {{if not .NewestVersion.IsZero}}
+                        <div class="meta-item">
+                            <span class="meta-label">Newest:</span>
+                            <span class="date">{{.NewestVersion.Format "2006-01-02"}}</span>
+                        </div>
+                        {{end}}
+                    </div>
+                    <div class="versions-list">
+                        {{range .VersionDetails}}
+                        <div class="version-item">
+                            <div>
+                                <span class="version-number">{{.Version}}</span>
+                                {{if not .Created.IsZero}}
+                                <span class="version-date"> • {{.Created.Format "2006-01-02"}}</span>
+                                {{end}}
+                                {{if .Signature}}
+                                <span class="version-sig sig-badge sig-{{.Signature}}">{{.Signature}}</span>
+                                {{end}}
+                            </div>
+                            {{if .URL}}
+                            <a href="{{publicURL .URL}}" class="version-link" target="_blank">Download</a>
+                            {{end}}
+                        </div>
+                        {{end}}
+                    </div>
+                </div>
+                {{end}}
+            </div>
+            {{if gt .Pages 1}}
+            <div class="pagination">
+                {{if .PrevURL}}<a href="{{.PrevURL}}">← Previous</a>{{else}}<span></span>{{end}}
+                <span>Page {{.Query.Page}} of {{.Pages}}</span>
+                {{if .NextURL}}<a href="{{.NextURL}}">Next →</a>{{else}}<span></span>{{end}}
+            </div>
+            {{end}}
+            {{else}}
+            <div class="no-results" id="noResults">
+                No charts match your filter criteria
+            </div>
+            {{end}}
+        </div>
+    </div>
+
+    <script>
+        // Toggle versions list
+        function toggleVersions(badge) {
+            const chartItem = badge.closest('.chart-item');
+            const versionsList = chartItem.querySelector('.versions-list');
+            const expandIcon = badge.querySelector('.expand-icon');
+            
+            versionsList.classList.toggle('expanded');
+            expandIcon.classList.toggle('expanded-icon');
+        }
+
+        const chartFilter = document.getElementById('chartFilter');
+
+        // Keyboard shortcuts
+        document.addEventListener('keydown', (e) => {
+            // Focus search on '/' key
+            if (e.key === '/' && document.activeElement !== chartFilter) {
+                e.preventDefault();
+                chartFilter.focus();
+            }
+            // Clear search on 'Escape' key
+            if (e.key === 'Escape' && document.activeElement === chartFilter) {
+                chartFilter.value = '';
+                chartFilter.form.submit();
+            }
+        });
+    </script>
+</body>
+</html>
+`
+
+// styleTemplate is the stylesheet shared by the dashboard pages
+const styleTemplate = `{{define "style"}}
     <style>
         * {
             margin: 0;
@@ -345,6 +687,28 @@ const htmlTemplate = `<!DOCTYPE html>
         .chart-item.hidden {
             display: none;
         }
+        .chart-item.deprecated {
+            opacity: 0.55;
+            background: #f7fafc;
+        }
+        .deprecated-tag {
+            background: #a0aec0;
+            color: white;
+            padding: 2px 8px;
+            border-radius: 10px;
+            font-size: 11px;
+            font-weight: 600;
+            margin-left: 8px;
+            vertical-align: middle;
+        }
+        .filter-toggle {
+            display: flex;
+            align-items: center;
+            gap: 8px;
+            font-size: 14px;
+            color: #4a5568;
+            cursor: pointer;
+        }
         .chart-header {
             display: flex;
             align-items: center;
@@ -454,211 +818,212 @@ const htmlTemplate = `<!DOCTYPE html>
         .expanded-icon {
             transform: rotate(180deg);
         }
+        .sig-badge {
+            padding: 4px 10px;
+            border-radius: 12px;
+            font-size: 12px;
+            font-weight: 600;
+            white-space: nowrap;
+        }
+        .sig-signed {
+            background: #c6f6d5;
+            color: #22543d;
+        }
+        .sig-unsigned {
+            background: #edf2f7;
+            color: #4a5568;
+        }
+        .sig-invalid {
+            background: #fed7d7;
+            color: #822727;
+        }
+        .version-sig {
+            font-size: 11px;
+            margin-left: 8px;
+        }
+        .lint-container {
+            background: white;
+            border-radius: 10px;
+            padding: 20px 30px;
+            margin-bottom: 20px;
+            box-shadow: 0 4px 6px rgba(0,0,0,0.1);
+        }
+        .lint-container summary {
+            cursor: pointer;
+            font-weight: 600;
+            color: #2d3748;
+        }
+        .lint-table {
+            width: 100%;
+            border-collapse: collapse;
+            margin-top: 15px;
+            font-size: 13px;
+        }
+        .lint-table th, .lint-table td {
+            text-align: left;
+            padding: 6px 10px;
+            border-bottom: 1px solid #e2e8f0;
+        }
+        .lint-table th {
+            color: #4a5568;
+            text-transform: uppercase;
+            font-size: 11px;
+            letter-spacing: 0.5px;
+        }
+        .severity {
+            padding: 2px 8px;
+            border-radius: 10px;
+            font-size: 11px;
+            font-weight: 600;
+        }
+        .severity-error {
+            background: #fed7d7;
+            color: #822727;
+        }
+        .severity-warning {
+            background: #fefcbf;
+            color: #744210;
+        }
+        .severity-info {
+            background: #bee3f8;
+            color: #2a4365;
+        }
+        .pagination {
+            display: flex;
+            justify-content: space-between;
+            align-items: center;
+            margin-top: 20px;
+            color: #4a5568;
+            font-size: 14px;
+        }
+        .pagination a {
+            color: #667eea;
+            font-weight: 600;
+            text-decoration: none;
+        }
+        .health {
+            padding: 2px 8px;
+            border-radius: 10px;
+            font-size: 11px;
+            font-weight: 600;
+        }
+        .health-ok {
+            background: #c6f6d5;
+            color: #22543d;
+        }
+        .health-failing {
+            background: #fed7d7;
+            color: #822727;
+        }
+        .health-stale {
+            background: #fefcbf;
+            color: #744210;
+        }
+        .health-pending {
+            background: #edf2f7;
+            color: #4a5568;
+        }
         .no-results {
             text-align: center;
             padding: 40px;
             color: #718096;
             font-size: 16px;
         }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>🚀 Helm Repository Dashboard</h1>
-            <p class="subtitle">Generated: {{.Generated.Format "2006-01-02 15:04:05 MST"}}</p>
-        </div>
-
-        <div class="stats">
-            <div class="stat-card">
-                <div class="stat-value">{{.Analysis.TotalCharts}}</div>
-                <div class="stat-label">Total Charts</div>
-            </div>
-            <div class="stat-card">
-                <div class="stat-value">{{.Analysis.TotalVersions}}</div>
-                <div class="stat-label">Total Versions</div>
-            </div>
-            {{if not .Analysis.OldestChartDate.IsZero}}
-            <div class="stat-card">
-                <div class="stat-value">{{.Analysis.OldestChartDate.Format "2006-01-02"}}</div>
-                <div class="stat-label">Oldest Chart</div>
-            </div>
-            {{end}}
-            {{if not .Analysis.NewestChartDate.IsZero}}
-            <div class="stat-card">
-                <div class="stat-value">{{.Analysis.NewestChartDate.Format "2006-01-02"}}</div>
-                <div class="stat-label">Newest Chart</div>
-            </div>
-            {{end}}
-        </div>
-
-        <div class="filters">
-            <div class="filter-group">
-                <label class="filter-label" for="repoFilter">Filter by Repository</label>
-                <select id="repoFilter" class="filter-input">
-                    <option value="">All Repositories</option>
-                </select>
-            </div>
-            <div class="filter-group">
-                <label class="filter-label" for="chartFilter">Filter by Chart Name</label>
-                <input type="text" id="chartFilter" class="filter-input" placeholder="Type to filter charts...">
-            </div>
-            <div class="filter-stats" id="filterStats">
-                Showing <span id="visibleCount">{{.Analysis.TotalCharts}}</span> of {{.Analysis.TotalCharts}} charts
-            </div>
-        </div>
-
-        <div class="charts-container">
-            <h2 style="margin-bottom: 20px; color: #2d3748;">📦 Available Charts</h2>
-            <div class="charts-grid" id="chartsGrid">
-                {{range .Analysis.ChartsInfo}}
-                <div class="chart-item" data-chart-name="{{.Name}}" data-repository="{{.Repository}}">
-                    <div class="chart-header">
-                        <div class="chart-title-section">
-                            {{if .Icon}}
-                            <img src="{{safeIconURL .Icon}}" alt="{{.Name}}" class="chart-icon" onerror="this.style.display='none'">
-                            {{end}}
-                            <div>
-                                <div class="chart-name">{{.Name}}</div>
-                                {{if .Repository}}
-                                <div style="font-size: 12px; color: #667eea; font-weight: 600; margin-top: 2px;">📁 {{.Repository}}</div>
-                                {{end}}
-                            </div>
-                        </div>
-                    </div>
-                    {{if .Description}}
-                    <div class="chart-description">{{.Description}}</div>
-                    {{end}}
-                    <div class="chart-meta">
-                        <div class="meta-item">
-                            <span class="badge" onclick="toggleVersions(this)">
-                                {{.VersionCount}} versions
-                                <span class="expand-icon">▼</span>
-                            </span>
-                        </div>
-                        {{if not .OldestVersion.IsZero}}
-                        <div class="meta-item">
-                            <span class="meta-label">Oldest:</span>
-                            <span class="date">{{.OldestVersion.Format "2006-01-02"}}</span>
-                        </div>
-                        {{end}}
-                        {{if not .NewestVersion.IsZero}}
-                        <div class="meta-item">
-                            <span class="meta-label">Newest:</span>
-                            <span class="date">{{.NewestVersion.Format "2006-01-02"}}</span>
-                        </div>
-                        {{end}}
-                    </div>
-                    <div class="versions-list">
-                        {{range .VersionDetails}}
-                        <div class="version-item">
-                            <div>
-                                <span class="version-number">{{.Version}}</span>
-                                {{if not .Created.IsZero}}
-                                <span class="version-date"> • {{.Created.Format "2006-01-02"}}</span>
-                                {{end}}
-                            </div>
-                            {{if .URL}}
-                            <a href="{{.URL}}" class="version-link" target="_blank">Download</a>
-                            {{end}}
-                        </div>
-                        {{end}}
-                    </div>
-                </div>
-                {{end}}
-            </div>
-            <div class="no-results" id="noResults" style="display: none;">
-                No charts match your filter criteria
-            </div>
-        </div>
-    </div>
-
-    <script>
-        // Toggle versions list
-        function toggleVersions(badge) {
-            const chartItem = badge.closest('.chart-item');
-            const versionsList = chartItem.querySelector('.versions-list');
-            const expandIcon = badge.querySelector('.expand-icon');
-            
-            versionsList.classList.toggle('expanded');
-            expandIcon.classList.toggle('expanded-icon');
+        .back-link {
+            color: white;
+            text-decoration: none;
+            font-weight: 600;
+            display: inline-block;
+            margin-bottom: 15px;
         }
-
-        // Filter functionality
-        const repoFilter = document.getElementById('repoFilter');
-        const chartFilter = document.getElementById('chartFilter');
-        const chartsGrid = document.getElementById('chartsGrid');
-        const noResults = document.getElementById('noResults');
-        const visibleCount = document.getElementById('visibleCount');
-        const chartItems = document.querySelectorAll('.chart-item');
-
-        // Populate repository dropdown
-        const repositories = new Set();
-        chartItems.forEach(item => {
-            const repo = item.dataset.repository;
-            if (repo) {
-                repositories.add(repo);
-            }
-        });
-        
-        // Sort repositories alphabetically
-        const sortedRepos = Array.from(repositories).sort();
-        sortedRepos.forEach(repo => {
-            const option = document.createElement('option');
-            option.value = repo;
-            option.textContent = repo;
-            repoFilter.appendChild(option);
-        });
-
-        function filterCharts() {
-            const repoQuery = repoFilter.value.toLowerCase();
-            const chartQuery = chartFilter.value.toLowerCase();
-            let visible = 0;
-
-            chartItems.forEach(item => {
-                const chartName = item.dataset.chartName.toLowerCase();
-                const repository = item.dataset.repository.toLowerCase();
-                
-                const repoMatches = !repoQuery || repository === repoQuery;
-                const chartMatches = !chartQuery || chartName.includes(chartQuery);
-
-                if (repoMatches && chartMatches) {
-                    item.classList.remove('hidden');
-                    visible++;
-                } else {
-                    item.classList.add('hidden');
-                }
-            });
-
-            visibleCount.textContent = visible;
-            
-            if (visible === 0) {
-                chartsGrid.style.display = 'none';
-                noResults.style.display = 'block';
-            } else {
-                chartsGrid.style.display = 'grid';
-                noResults.style.display = 'none';
-            }
+        .chart-link {
+            color: inherit;
+            text-decoration: none;
         }
-
-        repoFilter.addEventListener('change', filterCharts);
-        chartFilter.addEventListener('input', filterCharts);
-
-        // Keyboard shortcuts
-        document.addEventListener('keydown', (e) => {
-            // Focus search on '/' key
-            if (e.key === '/' && document.activeElement !== chartFilter) {
-                e.preventDefault();
-                chartFilter.focus();
-            }
-            // Clear search on 'Escape' key
-            if (e.key === 'Escape' && document.activeElement === chartFilter) {
-                chartFilter.value = '';
-                filterCharts();
-                chartFilter.blur();
-            }
-        });
-    </script>
-</body>
-</html>
-`
+        .chart-link:hover {
+            color: #667eea;
+        }
+        .detail-section {
+            background: white;
+            border-radius: 10px;
+            padding: 20px 30px;
+            margin-bottom: 20px;
+            box-shadow: 0 4px 6px rgba(0,0,0,0.1);
+        }
+        .detail-section h2 {
+            color: #2d3748;
+            font-size: 18px;
+            margin-bottom: 15px;
+        }
+        .detail-list {
+            display: grid;
+            grid-template-columns: max-content 1fr;
+            gap: 8px 20px;
+            font-size: 14px;
+            color: #4a5568;
+        }
+        .detail-list dt {
+            font-weight: 600;
+        }
+        .detail-list a, .lint-table a {
+            color: #667eea;
+        }
+        .keyword {
+            background: #edf2f7;
+            color: #4a5568;
+            padding: 2px 8px;
+            border-radius: 10px;
+            font-size: 12px;
+            margin-right: 4px;
+        }
+        .snippet {
+            display: flex;
+            align-items: center;
+            gap: 10px;
+            background: #2d3748;
+            color: #f7fafc;
+            border-radius: 8px;
+            padding: 10px 15px;
+            margin-bottom: 10px;
+            font-family: 'Courier New', monospace;
+            font-size: 13px;
+        }
+        .snippet code {
+            flex: 1;
+            overflow-x: auto;
+            white-space: nowrap;
+        }
+        .copy-button {
+            background: #667eea;
+            color: white;
+            border: none;
+            border-radius: 4px;
+            padding: 4px 10px;
+            font-size: 12px;
+            cursor: pointer;
+        }
+        .timeline {
+            display: flex;
+            align-items: flex-end;
+            gap: 2px;
+            height: 120px;
+            border-bottom: 1px solid #e2e8f0;
+        }
+        .timeline-bar {
+            flex: 1;
+            background: #667eea;
+            border-radius: 2px 2px 0 0;
+            min-height: 1px;
+        }
+        .timeline-bar.empty {
+            background: #e2e8f0;
+        }
+        .timeline-labels {
+            display: flex;
+            justify-content: space-between;
+            color: #718096;
+            font-size: 12px;
+            margin-top: 5px;
+        }
+    </style>
+{{end}}`
@@ -11,7 +11,7 @@ import (
 
 func TestSanitizeIconURL_Internal(t *testing.T) {
 	// Test through the template function since sanitizeIconURL is not exported
-	gen, err := NewHTMLGenerator()
+	gen, err := NewHTMLGenerator("/charts")
 	if err != nil {
 		t.Fatalf("Failed to create HTML generator: %v", err)
 	}
@@ -134,7 +134,7 @@ func TestSanitizeIconURL_Internal(t *testing.T) {
 				},
 			}
 
-			gen.Update(analysis)
+			gen.UpdateRepository("test", analysis)
 			req := httptest.NewRequest("GET", "/charts", nil)
 			w := httptest.NewRecorder()
 			gen.ServeHTTP(w, req)
@@ -158,7 +158,7 @@ func TestSanitizeIconURL_Internal(t *testing.T) {
 
 func TestHTMLGenerator_IconRendering(t *testing.T) {
 	// Create HTML generator
-	gen, err := NewHTMLGenerator()
+	gen, err := NewHTMLGenerator("/charts")
 	if err != nil {
 		t.Fatalf("Failed to create HTML generator: %v", err)
 	}
@@ -203,7 +203,7 @@ func TestHTMLGenerator_IconRendering(t *testing.T) {
 		},
 	}
 
-	gen.Update(analysis)
+	gen.UpdateRepository("test-repo", analysis)
 
 	// Create test request
 	req := httptest.NewRequest("GET", "/charts", nil)
@@ -248,7 +248,7 @@ func TestHTMLGenerator_IconRendering(t *testing.T) {
 }
 
 func TestHTMLGenerator_XSSProtection(t *testing.T) {
-	gen, err := NewHTMLGenerator()
+	gen, err := NewHTMLGenerator("/charts")
 	if err != nil {
 		t.Fatalf("Failed to create HTML generator: %v", err)
 	}
@@ -279,7 +279,7 @@ func TestHTMLGenerator_XSSProtection(t *testing.T) {
 			},
 		}
 
-		gen.Update(analysis)
+		gen.UpdateRepository("test-repo", analysis)
 
 		req := httptest.NewRequest("GET", "/charts", nil)
 		w := httptest.NewRecorder()
@@ -315,3 +315,41 @@ func TestHTMLGenerator_XSSProtection(t *testing.T) {
 		}
 	}
 }
+
+func TestHTMLGenerator_DeprecatedCharts(t *testing.T) {
+	gen, err := NewHTMLGenerator("/charts")
+	if err != nil {
+		t.Fatalf("Failed to create HTML generator: %v", err)
+	}
+
+	analysis := &analyzer.ChartAnalysis{
+		TotalCharts:      2,
+		TotalVersions:    2,
+		DeprecatedCharts: 1,
+		ChartsInfo: []analyzer.ChartInfo{
+			{Name: "active-chart", Repository: "test-repo", VersionCount: 1},
+			{Name: "old-chart", Repository: "test-repo", VersionCount: 1, Deprecated: true},
+		},
+	}
+
+	gen.UpdateRepository("test-repo", analysis)
+
+	req := httptest.NewRequest("GET", "/charts", nil)
+	w := httptest.NewRecorder()
+	gen.ServeHTTP(w, req)
+
+	body := w.Body.String()
+
+	if !strings.Contains(body, `class="chart-item deprecated" data-chart-name="old-chart"`) {
+		t.Error("Deprecated chart is not rendered with the deprecated class")
+	}
+	if strings.Contains(body, `class="chart-item deprecated" data-chart-name="active-chart"`) {
+		t.Error("Active chart is rendered with the deprecated class")
+	}
+	if !strings.Contains(body, `id="deprecatedFilter"`) {
+		t.Error("Deprecated filter toggle not found in HTML output")
+	}
+	if !strings.Contains(body, "Deprecated Charts") {
+		t.Error("Deprecated charts stat card not found in HTML output")
+	}
+}
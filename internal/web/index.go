@@ -0,0 +1,125 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+// IndexGroup is a merged, filtered index built from one or more repositories
+type IndexGroup struct {
+	Name         string
+	Repositories []string // Merged in order, the first one defining a chart version wins
+	Filter       analyzer.IndexFilter
+}
+
+// IndexProxyHandler serves merged repository indexes at /index/{group}/index.yaml
+type IndexProxyHandler struct {
+	groups map[string]IndexGroup
+
+	mu       sync.RWMutex
+	sources  map[string]analyzer.IndexSource // Latest index entries per repository
+	rendered map[string][]byte               // Cached index.yaml per group
+}
+
+// NewIndexProxyHandler creates a handler serving the given groups
+func NewIndexProxyHandler(groups []IndexGroup) *IndexProxyHandler {
+	h := &IndexProxyHandler{
+		groups:   make(map[string]IndexGroup, len(groups)),
+		sources:  make(map[string]analyzer.IndexSource),
+		rendered: make(map[string][]byte),
+	}
+	for _, group := range groups {
+		h.groups[group.Name] = group
+	}
+	return h
+}
+
+// Includes reports whether any group uses the repository, i.e. whether its entries
+// have to be kept for Update
+func (h *IndexProxyHandler) Includes(repository string) bool {
+	for _, group := range h.groups {
+		for _, name := range group.Repositories {
+			if name == repository {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Update replaces the index entries of a repository
+func (h *IndexProxyHandler) Update(repository, repoURL string, entries map[string][]analyzer.ChartVersionInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sources[repository] = analyzer.IndexSource{RepoURL: repoURL, Entries: entries}
+	for _, group := range h.groups {
+		for _, name := range group.Repositories {
+			if name == repository {
+				delete(h.rendered, group.Name)
+				break
+			}
+		}
+	}
+}
+
+// ServeHTTP renders the merged index of the group named in the path
+func (h *IndexProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/index/"), "/index.yaml")
+	group, known := h.groups[name]
+	if !ok || !known {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := h.render(group)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		http.Error(w, "No data available yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	_, _ = w.Write(data)
+}
+
+// render returns the cached index.yaml of a group, building it if needed.
+// It returns nil until at least one of the group's repositories has been scraped.
+func (h *IndexProxyHandler) render(group IndexGroup) ([]byte, error) {
+	h.mu.RLock()
+	data := h.rendered[group.Name]
+	h.mu.RUnlock()
+	if data != nil {
+		return data, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if data := h.rendered[group.Name]; data != nil {
+		return data, nil
+	}
+
+	var sources []analyzer.IndexSource
+	for _, name := range group.Repositories {
+		if source, ok := h.sources[name]; ok {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := analyzer.WriteIndex(&buf, analyzer.MergeIndexes(sources, group.Filter)); err != nil {
+		return nil, err
+	}
+	h.rendered[group.Name] = buf.Bytes()
+	return buf.Bytes(), nil
+}
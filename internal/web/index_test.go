@@ -0,0 +1,53 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+func TestIndexProxyHandler(t *testing.T) {
+	h := NewIndexProxyHandler([]IndexGroup{{Name: "curated", Repositories: []string{"stable"}}})
+	if !h.Includes("stable") || h.Includes("other") {
+		t.Error("Includes reports the wrong repositories")
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/index/curated/index.yaml"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before the first scrape, got %d", rec.Code)
+	}
+	if rec := get("/index/unknown/index.yaml"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown group, got %d", rec.Code)
+	}
+
+	h.Update("stable", "https://charts.example.com/index.yaml", map[string][]analyzer.ChartVersionInfo{
+		"app": {{Name: "app", Version: "1.0.0", URLs: []string{"app-1.0.0.tgz"}}},
+	})
+	rec := get("/index/curated/index.yaml")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	index, err := analyzer.ParseIndex(rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Served index does not parse: %v", err)
+	}
+	if got := index.Entries["app"][0].URLs[0]; got != "https://charts.example.com/app-1.0.0.tgz" {
+		t.Errorf("Expected absolute chart URL, got %q", got)
+	}
+
+	// Updates invalidate the cached rendering
+	h.Update("stable", "https://charts.example.com/index.yaml", map[string][]analyzer.ChartVersionInfo{
+		"app": {{Name: "app", Version: "2.0.0", URLs: []string{"app-2.0.0.tgz"}}},
+	})
+	index, err = analyzer.ParseIndex(get("/index/curated/index.yaml").Body.Bytes())
+	if err != nil || index.Entries["app"][0].Version != "2.0.0" {
+		t.Errorf("Expected updated index, got %v (%v)", index, err)
+	}
+}
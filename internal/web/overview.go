@@ -0,0 +1,116 @@
+package web
+
+import (
+	"sort"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+	"github.com/obezpalko/helm-repo-exporter/internal/logging"
+)
+
+// RepositoryInfo describes a configured repository in the repository overview
+type RepositoryInfo struct {
+	Name         string
+	URL          string
+	Auth         string // Authentication methods, such as "none" or "basic"
+	ScanInterval time.Duration
+}
+
+// repositoryRow is the state of a repository shown in the overview
+type repositoryRow struct {
+	RepositoryInfo
+	analysis    *analyzer.ChartAnalysis // Latest successful analysis, nil before the first one
+	LastSuccess time.Time
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// Health summarizes the row: pending before the first scrape, failing when the latest
+// scrape failed, stale when no scrape succeeded for two scan intervals, and ok otherwise
+func (r *repositoryRow) Health(now time.Time) string {
+	switch {
+	case r.LastErrorAt.After(r.LastSuccess):
+		return "failing"
+	case r.LastSuccess.IsZero():
+		return "pending"
+	case r.ScanInterval > 0 && now.Sub(r.LastSuccess) > 2*r.ScanInterval:
+		return "stale"
+	default:
+		return "ok"
+	}
+}
+
+// Charts returns the number of charts of the latest successful analysis
+func (r *repositoryRow) Charts() int {
+	if r.analysis == nil {
+		return 0
+	}
+	return r.analysis.TotalCharts
+}
+
+// Versions returns the number of versions of the latest successful analysis
+func (r *repositoryRow) Versions() int {
+	if r.analysis == nil {
+		return 0
+	}
+	return r.analysis.TotalVersions
+}
+
+// IndexSize returns the decoded index size of the latest successful analysis, 0 if unknown
+func (r *repositoryRow) IndexSize() int64 {
+	if r.analysis == nil {
+		return 0
+	}
+	return r.analysis.IndexSize
+}
+
+// SetRepositories sets the configured repositories listed in the overview
+func (h *HTMLGenerator) SetRepositories(repositories []RepositoryInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, info := range repositories {
+		info.URL = publicURL(info.URL)
+		if row := h.repositories[info.Name]; row != nil {
+			row.RepositoryInfo = info
+			continue
+		}
+		h.repositories[info.Name] = &repositoryRow{RepositoryInfo: info}
+	}
+}
+
+// SetSecrets sets the configured secrets removed from the scrape errors shown in the overview.
+// URL credentials are removed without them.
+func (h *HTMLGenerator) SetSecrets(secrets []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.redactor = logging.NewRedactor(secrets)
+}
+
+// RecordScrape records the outcome of a repository scrape: its analysis, or the error it failed with
+func (h *HTMLGenerator) RecordScrape(repository string, analysis *analyzer.ChartAnalysis, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	row := h.repositories[repository]
+	if row == nil {
+		row = &repositoryRow{RepositoryInfo: RepositoryInfo{Name: repository}}
+		h.repositories[repository] = row
+	}
+	if err != nil {
+		row.LastError, row.LastErrorAt = h.redactor.Redact(err.Error()), time.Now()
+		return
+	}
+	row.analysis, row.LastSuccess = analysis, time.Now()
+}
+
+// overviewRows returns copies of the overview rows, sorted by repository name
+func (h *HTMLGenerator) overviewRows() []*repositoryRow {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rows := make([]*repositoryRow, 0, len(h.repositories))
+	for _, row := range h.repositories {
+		copied := *row
+		rows = append(rows, &copied)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	return rows
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+// Dashboard page sizes
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// Dashboard sort orders
+const (
+	sortByName     = "name"     // Alphabetically, then by repository
+	sortByAge      = "age"      // Most recently released first
+	sortByVersions = "versions" // Most versions first
+)
+
+// dashboardQuery selects the charts listed on a dashboard page. It is read from and written
+// to the query string, so that a filtered view can be shared as a URL.
+type dashboardQuery struct {
+	Search         string // Matched against chart names, descriptions and keywords
+	Repository     string
+	Sort           string
+	HideDeprecated bool
+	Page           int // 1-based
+	Limit          int
+}
+
+// parseDashboardQuery reads the q, repository, sort, hideDeprecated, page and limit parameters
+func parseDashboardQuery(values url.Values) (dashboardQuery, error) {
+	q := dashboardQuery{
+		Search:         strings.TrimSpace(values.Get("q")),
+		Repository:     values.Get("repository"),
+		Sort:           values.Get("sort"),
+		HideDeprecated: values.Get("hideDeprecated") != "",
+		Page:           1,
+		Limit:          defaultPageLimit,
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = sortByName
+	case sortByName, sortByAge, sortByVersions:
+	default:
+		return q, fmt.Errorf("sort must be %s, %s or %s", sortByName, sortByAge, sortByVersions)
+	}
+
+	var err error
+	if page := values.Get("page"); page != "" {
+		if q.Page, err = strconv.Atoi(page); err != nil || q.Page < 1 {
+			return q, fmt.Errorf("invalid page %q", page)
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 1 {
+			return q, fmt.Errorf("invalid limit %q", limit)
+		}
+		q.Limit = min(q.Limit, maxPageLimit)
+	}
+	return q, nil
+}
+
+// matches reports whether a chart passes the query's filters
+func (q dashboardQuery) matches(chart *analyzer.ChartInfo) bool {
+	if q.Repository != "" && chart.Repository != q.Repository {
+		return false
+	}
+	if q.HideDeprecated && chart.Deprecated {
+		return false
+	}
+	if q.Search == "" {
+		return true
+	}
+
+	search := strings.ToLower(q.Search)
+	if strings.Contains(strings.ToLower(chart.Name), search) || strings.Contains(strings.ToLower(chart.Description), search) {
+		return true
+	}
+	for _, keyword := range chart.Keywords {
+		if strings.Contains(strings.ToLower(keyword), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the charts of the requested page, in the requested order, and the number of matching charts
+func (q dashboardQuery) apply(charts []analyzer.ChartInfo) ([]analyzer.ChartInfo, int) {
+	var matching []analyzer.ChartInfo
+	for i := range charts {
+		if q.matches(&charts[i]) {
+			matching = append(matching, charts[i])
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		a, b := &matching[i], &matching[j]
+		switch q.Sort {
+		case sortByAge:
+			if !a.NewestVersion.Equal(b.NewestVersion) {
+				return a.NewestVersion.After(b.NewestVersion)
+			}
+		case sortByVersions:
+			if a.VersionCount != b.VersionCount {
+				return a.VersionCount > b.VersionCount
+			}
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Repository < b.Repository
+	})
+
+	start := min((q.Page-1)*q.Limit, len(matching))
+	end := min(start+q.Limit, len(matching))
+	return matching[start:end], len(matching)
+}
+
+// url returns the query string of another page of the same view, leaving out defaults
+func (q dashboardQuery) url(page int) string {
+	values := url.Values{}
+	if q.Search != "" {
+		values.Set("q", q.Search)
+	}
+	if q.Repository != "" {
+		values.Set("repository", q.Repository)
+	}
+	if q.Sort != sortByName {
+		values.Set("sort", q.Sort)
+	}
+	if q.HideDeprecated {
+		values.Set("hideDeprecated", "1")
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if q.Limit != defaultPageLimit {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if len(values) == 0 {
+		return "?"
+	}
+	return "?" + values.Encode()
+}
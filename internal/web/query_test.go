@@ -0,0 +1,111 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obezpalko/helm-repo-exporter/internal/analyzer"
+)
+
+func testCharts() []analyzer.ChartInfo {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	return []analyzer.ChartInfo{
+		{Name: "nginx", Repository: "stable", VersionCount: 3, NewestVersion: day(2), Description: "Web server"},
+		{Name: "redis", Repository: "stable", VersionCount: 9, NewestVersion: day(5), Keywords: []string{"cache"}},
+		{Name: "nginx", Repository: "incubator", VersionCount: 1, NewestVersion: day(9)},
+		{Name: "legacy", Repository: "stable", VersionCount: 5, NewestVersion: day(1), Deprecated: true},
+	}
+}
+
+func TestDashboardQuery(t *testing.T) {
+	names := func(charts []analyzer.ChartInfo) string {
+		var names []string
+		for _, c := range charts {
+			names = append(names, c.Repository+"/"+c.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+		matching int
+	}{
+		{"", "stable/legacy,incubator/nginx,stable/nginx,stable/redis", 4},
+		{"q=WEB", "stable/nginx", 1},
+		{"q=cache", "stable/redis", 1},
+		{"repository=stable&hideDeprecated=1", "stable/nginx,stable/redis", 2},
+		{"sort=age", "incubator/nginx,stable/redis,stable/nginx,stable/legacy", 4},
+		{"sort=versions&limit=2", "stable/redis,stable/legacy", 4},
+		{"sort=versions&limit=2&page=2", "stable/nginx,incubator/nginx", 4},
+		{"page=9", "", 4},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		q, err := parseDashboardQuery(values)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+		charts, matching := q.apply(testCharts())
+		if got := names(charts); got != tt.expected || matching != tt.matching {
+			t.Errorf("%q: expected %s (%d matching), got %s (%d)", tt.query, tt.expected, tt.matching, got, matching)
+		}
+	}
+
+	for _, invalid := range []string{"sort=size", "page=0", "limit=x"} {
+		values, _ := url.ParseQuery(invalid)
+		if _, err := parseDashboardQuery(values); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+
+	values, _ := url.ParseQuery("q=web server&sort=age&limit=1000")
+	q, _ := parseDashboardQuery(values)
+	if got := q.url(2); got != "?limit=500&page=2&q=web+server&sort=age" {
+		t.Errorf("Unexpected page URL %q", got)
+	}
+}
+
+func TestHTMLGenerator_Pagination(t *testing.T) {
+	gen, err := NewHTMLGenerator("/charts")
+	if err != nil {
+		t.Fatalf("Failed to create HTML generator: %v", err)
+	}
+	analysis := &analyzer.ChartAnalysis{}
+	for i := 0; i < 120; i++ {
+		analysis.ChartsInfo = append(analysis.ChartsInfo, analyzer.ChartInfo{Name: fmt.Sprintf("chart-%03d", i), Repository: "stable"})
+	}
+	analysis.TotalCharts = len(analysis.ChartsInfo)
+	gen.UpdateRepository("stable", analysis)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		gen.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	body := get("/charts?page=2").Body.String()
+	if n := strings.Count(body, `class="chart-item`); n != defaultPageLimit {
+		t.Errorf("Expected %d charts on the page, got %d", defaultPageLimit, n)
+	}
+	for _, expected := range []string{"chart-050", "Showing 51–100 of 120 matching charts", "Page 2 of 3", `href="?"`, `href="?page=3"`} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Page does not contain %q", expected)
+		}
+	}
+	if strings.Contains(body, "chart-049") || strings.Contains(body, "chart-100") {
+		t.Error("Page lists charts of other pages")
+	}
+
+	if body := get("/charts?q=nothing").Body.String(); !strings.Contains(body, "No charts match your filter criteria") {
+		t.Error("Expected a no results message")
+	}
+	if w := get("/charts?sort=size"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown sort, got %d", w.Code)
+	}
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -17,6 +20,14 @@ type Config struct {
 	ScanInterval time.Duration `yaml:"scanInterval"`
 	ScanTimeout  time.Duration `yaml:"scanTimeout"`
 
+	// CollectOnScrape fetches repositories when Prometheus scrapes the exporter instead of on
+	// a timer; a repository is refetched at most once per scan interval
+	CollectOnScrape bool `yaml:"collectOnScrape,omitempty"`
+
+	// MaxIndexSize is the largest index.yaml, in bytes, the exporter will download
+	// Set to a negative value to disable the limit
+	MaxIndexSize int64 `yaml:"maxIndexSize"`
+
 	// Server Configuration
 	MetricsPort string `yaml:"metricsPort"`
 	MetricsPath string `yaml:"metricsPath"`
@@ -24,6 +35,154 @@ type Config struct {
 	// Optional Features
 	EnableHTML bool   `yaml:"enableHTML"`
 	HTMLPath   string `yaml:"htmlPath"`
+
+	// Index linting defaults, applied to every repository
+	Lint LintConfig `yaml:"lint,omitempty"`
+
+	// ChartSeries limits the number of per-chart metric series
+	ChartSeries ChartSeriesConfig `yaml:"chartSeries,omitempty"`
+
+	// IndexGroups are merged, filtered indexes served at /index/{name}/index.yaml
+	IndexGroups []IndexGroup `yaml:"indexGroups,omitempty"`
+
+	// EnableProbe serves /probe?target=<index url>&module=<name> for repositories that are not configured
+	EnableProbe bool `yaml:"enableProbe,omitempty"`
+
+	// ProbeModules are named auth and TLS settings selected by the module parameter of /probe
+	ProbeModules map[string]ProbeModule `yaml:"probeModules,omitempty"`
+
+	// ProbeTargets are the hosts that probes without a module may fetch, empty allows any host
+	ProbeTargets []string `yaml:"probeTargets,omitempty"`
+
+	// OTLP exports metrics and traces to an OpenTelemetry collector
+	// If not set, metrics are only served to Prometheus and no traces are recorded
+	OTLP *OTLPConfig `yaml:"otlp,omitempty"`
+
+	// Log configures the log output
+	Log LogConfig `yaml:"log,omitempty"`
+
+	// Notifications sends new chart versions to webhooks
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty"`
+}
+
+// Notification payload formats
+const (
+	NotifyFormatGeneric = "generic"
+	NotifyFormatSlack   = "slack"
+	NotifyFormatTeams   = "teams"
+)
+
+// NotificationsConfig defines the webhooks notified of new chart versions
+type NotificationsConfig struct {
+	// BatchInterval is how long new versions are collected before being sent together (default 1m)
+	BatchInterval time.Duration `yaml:"batchInterval,omitempty"`
+
+	// MaxRetries is the number of retries of a failed delivery (default 3)
+	MaxRetries int `yaml:"maxRetries,omitempty"`
+
+	// RetryBackoff is the delay before the first retry, doubled for every further retry (default 1s)
+	RetryBackoff time.Duration `yaml:"retryBackoff,omitempty"`
+
+	// DeadLetterFile receives undeliverable batches as JSON lines
+	// If not set, they are only logged
+	DeadLetterFile string `yaml:"deadLetterFile,omitempty"`
+
+	Subscriptions []Subscription `yaml:"subscriptions"`
+}
+
+// Subscription is a webhook notified of the new versions of selected charts
+type Subscription struct {
+	// Name identifies the subscription in logs and the dead-letter file
+	Name string `yaml:"name"`
+
+	// URL is the webhook endpoint
+	URL string `yaml:"url"`
+
+	// Format is "generic" (default), "slack" or "teams"
+	Format string `yaml:"format,omitempty"`
+
+	// Template is a Go template rendering the JSON body of generic webhooks
+	// It receives .Subscription and .Versions; the json function encodes a value
+	Template string `yaml:"template,omitempty"`
+
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Repositories limits the subscription to these repositories; empty means all
+	Repositories []string `yaml:"repositories,omitempty"`
+
+	// Charts selects chart names and versions, like a repository's chart filter
+	Charts ChartFilterConfig `yaml:"charts,omitempty"`
+}
+
+// LogConfig defines the format and verbosity of the logs
+type LogConfig struct {
+	// Format is "text" (default) or "json"
+	Format string `yaml:"format,omitempty"`
+
+	// Level is "debug", "info" (default), "warn" or "error"
+	Level string `yaml:"level,omitempty"`
+}
+
+func (l LogConfig) validate() error {
+	switch l.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("log.format must be \"text\" or \"json\", got %q", l.Format)
+	}
+	switch strings.ToLower(l.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log.level must be debug, info, warn or error, got %q", l.Level)
+	}
+	return nil
+}
+
+// Secrets returns every credential in the configuration, for redaction from logs
+func (c *Config) Secrets() []string {
+	var secrets []string
+	addURL := func(rawURL string) {
+		if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+			password, _ := u.User.Password()
+			secrets = append(secrets, u.User.Username(), password)
+		}
+	}
+	for _, repo := range c.Repositories {
+		secrets = append(secrets, repo.Auth.secrets()...)
+		addURL(repo.URL)
+		if gen := repo.GenerateIndex; gen != nil {
+			addURL(gen.Source)
+			if gen.Credentials != nil {
+				secrets = append(secrets, gen.Credentials.SecretAccessKey, gen.Credentials.SessionToken)
+			}
+		}
+	}
+	for _, module := range c.ProbeModules {
+		secrets = append(secrets, module.Auth.secrets()...)
+	}
+	if c.Notifications != nil {
+		for _, sub := range c.Notifications.Subscriptions {
+			// Slack and Teams webhook URLs embed their credentials
+			secrets = append(secrets, sub.URL)
+			for _, value := range sub.Headers {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	if c.OTLP != nil {
+		for _, value := range c.OTLP.Headers {
+			secrets = append(secrets, value)
+		}
+		addURL(c.OTLP.Endpoint)
+	}
+
+	nonEmpty := secrets[:0]
+	for _, secret := range secrets {
+		if secret != "" {
+			nonEmpty = append(nonEmpty, secret)
+		}
+	}
+	return nonEmpty
 }
 
 // Repository defines a Helm repository source
@@ -32,14 +191,299 @@ type Repository struct {
 	Name string `yaml:"name"`
 
 	// URL is the HTTP/HTTPS URL to the index.yaml file
+	// file:// URLs read the index, or a directory containing it, from the local filesystem
 	URL string `yaml:"url"`
 
+	// Watch rescans a file:// repository as soon as its index changes on disk,
+	// in addition to the regular scan interval
+	Watch bool `yaml:"watch,omitempty"`
+
 	// ScanInterval overrides the global scan interval for this repository
 	// If not set, uses the global scanInterval
 	ScanInterval time.Duration `yaml:"scanInterval,omitempty"`
 
+	// MaxIndexSize overrides the global maximum index size for this repository
+	MaxIndexSize int64 `yaml:"maxIndexSize,omitempty"`
+
 	// Authentication configuration
 	Auth *AuthConfig `yaml:"auth,omitempty"`
+
+	// TLS configures HTTPS connections to the repository
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+
+	// Provenance verification configuration
+	// If not set, .prov files are not checked
+	Provenance *ProvenanceConfig `yaml:"provenance,omitempty"`
+
+	// MirrorOf names another configured repository that this one mirrors
+	// The exporter reports upstream versions missing from this repository
+	MirrorOf string `yaml:"mirrorOf,omitempty"`
+
+	// Lint overrides the global lint configuration for this repository
+	// Severities are merged with the global ones and suppressed rules are added to them
+	Lint LintConfig `yaml:"lint,omitempty"`
+
+	// MaxChartSeries caps the per-chart metric series of this repository (0 for no limit)
+	MaxChartSeries int `yaml:"maxChartSeries,omitempty"`
+
+	// Charts restricts which charts and versions are analyzed and exported
+	Charts ChartFilterConfig `yaml:"charts,omitempty"`
+
+	// GenerateIndex builds the index from the chart tarballs themselves
+	// If not set, only the published index is used
+	GenerateIndex *GenerateIndexConfig `yaml:"generateIndex,omitempty"`
+}
+
+// GenerateIndex modes
+const (
+	// GenerateWhenMissing uses a generated index only when the published one cannot be read
+	GenerateWhenMissing = "missing"
+	// GenerateAlways ignores the published index
+	GenerateAlways = "always"
+)
+
+// GenerateIndexConfig controls generating an index from a directory or bucket of charts
+type GenerateIndexConfig struct {
+	// When is "missing" (default) or "always"
+	When string `yaml:"when,omitempty"`
+
+	// Source lists the chart tarballs: file:///path or s3://bucket/prefix
+	// Defaults to the repository URL's directory for file:// repositories
+	Source string `yaml:"source,omitempty"`
+
+	// Endpoint overrides the S3 endpoint, e.g. for S3-compatible storage
+	// Buckets are then addressed path-style as <endpoint>/<bucket>
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// Region of the S3 bucket, defaulting to AWS_REGION, AWS_DEFAULT_REGION or us-east-1
+	Region string `yaml:"region,omitempty"`
+
+	// Credentials sign S3 requests
+	// If not set, they are read from the AWS environment variables or shared credentials
+	// file, and requests are anonymous when neither has any
+	Credentials *S3Credentials `yaml:"credentials,omitempty"`
+}
+
+// S3Credentials are the AWS access keys of an S3 chart source
+type S3Credentials struct {
+	AccessKeyID     string `yaml:"accessKeyID"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	SessionToken    string `yaml:"sessionToken,omitempty"`
+}
+
+func (g *GenerateIndexConfig) validate(repo Repository) error {
+	if g == nil {
+		return nil
+	}
+	switch g.When {
+	case "", GenerateWhenMissing, GenerateAlways:
+	default:
+		return fmt.Errorf("generateIndex.when must be %q or %q, got %q", GenerateWhenMissing, GenerateAlways, g.When)
+	}
+	switch {
+	case g.Source == "" && !strings.HasPrefix(repo.URL, "file://"):
+		return fmt.Errorf("generateIndex.source is required for non-local repositories")
+	case g.Source != "" && !strings.HasPrefix(g.Source, "file://") && !strings.HasPrefix(g.Source, "s3://"):
+		return fmt.Errorf("generateIndex.source must be a file:// or s3:// URL, got %q", g.Source)
+	}
+	if c := g.Credentials; c != nil && (c.AccessKeyID == "" || c.SecretAccessKey == "") {
+		return fmt.Errorf("generateIndex.credentials requires accessKeyID and secretAccessKey")
+	}
+	return nil
+}
+
+// Chart series overflow modes
+const (
+	// OverflowTruncate keeps the most recently updated charts up to the limit
+	OverflowTruncate = "truncate"
+	// OverflowAggregate drops every per-chart series of a repository over its limit
+	OverflowAggregate = "aggregate"
+)
+
+// ChartSeriesConfig caps the helm_repo_chart_* series exported across all repositories
+type ChartSeriesConfig struct {
+	// Limit is the maximum number of per-chart series across all repositories (0 for no limit)
+	Limit int `yaml:"limit,omitempty"`
+
+	// Overflow is "truncate" (default) or "aggregate"
+	Overflow string `yaml:"overflow,omitempty"`
+}
+
+// ChartFilterConfig selects the charts and versions of a repository
+type ChartFilterConfig struct {
+	// Include lists chart name patterns to keep; empty keeps every chart
+	// Patterns are globs (nginx-*) or regular expressions wrapped in slashes (/^nginx-.*$/)
+	Include []string `yaml:"include,omitempty"`
+
+	// Exclude lists chart name patterns to drop, applied after Include
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	// Versions is a semver constraint (e.g. ">= 1.0.0") that kept versions must satisfy
+	Versions string `yaml:"versions,omitempty"`
+}
+
+// IndexGroup is a curated index built from one or more repositories
+type IndexGroup struct {
+	// Name is used in the URL: /index/{name}/index.yaml
+	Name string `yaml:"name"`
+
+	// Repositories are merged in order; the first one defining a chart version wins
+	Repositories []string `yaml:"repositories"`
+
+	// ExcludeDeprecated drops charts whose latest version is deprecated
+	ExcludeDeprecated bool `yaml:"excludeDeprecated,omitempty"`
+
+	// LatestVersions keeps only the newest N versions of each chart (0 keeps all)
+	LatestVersions int `yaml:"latestVersions,omitempty"`
+
+	// Charts is an allowlist of chart names; empty includes every chart
+	Charts []string `yaml:"charts,omitempty"`
+}
+
+// LintConfig controls index linting
+type LintConfig struct {
+	// Severity overrides the default severity (error, warning, info) of individual rules
+	Severity map[string]string `yaml:"severity,omitempty"`
+
+	// Suppress lists rules that are not checked
+	Suppress []string `yaml:"suppress,omitempty"`
+}
+
+// OTLP protocols
+const (
+	OTLPProtocolGRPC = "grpc"
+	OTLPProtocolHTTP = "http"
+)
+
+// OTLPConfig defines where and how metrics and traces are exported over OTLP
+type OTLPConfig struct {
+	// Endpoint is host:port, or a URL whose scheme selects TLS (https) or plaintext (http)
+	Endpoint string `yaml:"endpoint"`
+
+	// Protocol is "grpc" (default) or "http" (protobuf over HTTP)
+	Protocol string `yaml:"protocol,omitempty"`
+
+	// Insecure disables TLS for host:port endpoints
+	Insecure bool `yaml:"insecure,omitempty"`
+
+	// Headers are sent with every export, e.g. for authentication
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Interval between metric exports (default 1m)
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+func (o *OTLPConfig) validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Endpoint == "" {
+		return fmt.Errorf("otlp.endpoint is required")
+	}
+	switch o.Protocol {
+	case "", OTLPProtocolGRPC, OTLPProtocolHTTP:
+	default:
+		return fmt.Errorf("otlp.protocol must be %q or %q, got %q", OTLPProtocolGRPC, OTLPProtocolHTTP, o.Protocol)
+	}
+	return nil
+}
+
+func (n *NotificationsConfig) validate(repositories map[string]bool) error {
+	if n == nil {
+		return nil
+	}
+	if n.MaxRetries < 0 {
+		return fmt.Errorf("notifications.maxRetries cannot be negative")
+	}
+	subscriptions := make(map[string]bool, len(n.Subscriptions))
+	for _, sub := range n.Subscriptions {
+		switch {
+		case sub.Name == "":
+			return fmt.Errorf("notification subscriptions need a name")
+		case subscriptions[sub.Name]:
+			return fmt.Errorf("duplicate notification subscription %q", sub.Name)
+		case !strings.HasPrefix(sub.URL, "http://") && !strings.HasPrefix(sub.URL, "https://"):
+			return fmt.Errorf("subscription %q: url must be an http or https URL", sub.Name)
+		}
+		subscriptions[sub.Name] = true
+		switch sub.Format {
+		case "", NotifyFormatGeneric, NotifyFormatSlack, NotifyFormatTeams:
+		default:
+			return fmt.Errorf("subscription %q: format must be %q, %q or %q, got %q",
+				sub.Name, NotifyFormatGeneric, NotifyFormatSlack, NotifyFormatTeams, sub.Format)
+		}
+		if sub.Template != "" && sub.Format != "" && sub.Format != NotifyFormatGeneric {
+			return fmt.Errorf("subscription %q: template is only used by the generic format", sub.Name)
+		}
+		for _, repo := range sub.Repositories {
+			if !repositories[repo] {
+				return fmt.Errorf("subscription %q includes unknown repository %q", sub.Name, repo)
+			}
+		}
+	}
+	return nil
+}
+
+// ProbeModule holds the settings used to probe a target
+type ProbeModule struct {
+	// Auth is sent to every target probed with this module
+	Auth *AuthConfig `yaml:"auth,omitempty"`
+
+	// Targets are the hosts this module may probe: exact names, or "*.example.com" for any
+	// subdomain. Required for modules with credentials; empty allows any host otherwise.
+	Targets []string `yaml:"targets,omitempty"`
+
+	// TLS configures HTTPS connections to the targets
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+
+	// MaxIndexSize overrides the global maximum index size
+	MaxIndexSize int64 `yaml:"maxIndexSize,omitempty"`
+}
+
+// TLSConfig defines the TLS settings of HTTPS connections
+type TLSConfig struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system ones
+	CAFile string `yaml:"caFile,omitempty"`
+
+	// CertFile and KeyFile are a PEM client certificate and key for mutual TLS
+	CertFile string `yaml:"certFile,omitempty"`
+	KeyFile  string `yaml:"keyFile,omitempty"`
+
+	// ServerName overrides the name used to verify the server certificate
+	ServerName string `yaml:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables server certificate verification
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify,omitempty"`
+}
+
+// HasCredentials reports whether the module sends auth or a client certificate to its targets
+func (m ProbeModule) HasCredentials() bool {
+	return m.Auth.Type() != "none" || (m.TLS != nil && m.TLS.CertFile != "")
+}
+
+// validateHostPatterns checks a list of host names or "*." wildcards
+func validateHostPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		host := strings.TrimPrefix(pattern, "*.")
+		if host == "" || strings.ContainsAny(host, "/:*@?# ") {
+			return fmt.Errorf("invalid target host %q", pattern)
+		}
+	}
+	return nil
+}
+
+func (t *TLSConfig) validate() error {
+	if t != nil && (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls.certFile and tls.keyFile must be set together")
+	}
+	return nil
+}
+
+// ProvenanceConfig defines how chart provenance (.prov) files are verified
+type ProvenanceConfig struct {
+	// Keyring is the path to an OpenPGP public keyring (binary or ASCII-armored)
+	// holding the keys allowed to sign charts in this repository
+	Keyring string `yaml:"keyring"`
 }
 
 // AuthConfig defines authentication methods
@@ -54,12 +498,48 @@ type AuthConfig struct {
 	Headers map[string]string `yaml:"headers,omitempty"`
 }
 
+// Type names the configured authentication methods, such as "basic" or "bearer+headers", or "none"
+func (a *AuthConfig) Type() string {
+	var methods []string
+	if a != nil && a.Basic != nil {
+		methods = append(methods, "basic")
+	}
+	if a != nil && a.BearerToken != "" {
+		methods = append(methods, "bearer")
+	}
+	if a != nil && len(a.Headers) > 0 {
+		methods = append(methods, "headers")
+	}
+	if len(methods) == 0 {
+		return "none"
+	}
+	return strings.Join(methods, "+")
+}
+
+func (a *AuthConfig) secrets() []string {
+	if a == nil {
+		return nil
+	}
+	var secrets []string
+	if a.Basic != nil {
+		secrets = append(secrets, a.Basic.Username, a.Basic.Password)
+	}
+	secrets = append(secrets, a.BearerToken)
+	for _, value := range a.Headers {
+		secrets = append(secrets, value)
+	}
+	return secrets
+}
+
 // BasicAuth holds username and password for basic authentication
 type BasicAuth struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 }
 
+// DefaultMaxIndexSize is large enough for the biggest public repositories
+const DefaultMaxIndexSize = 512 << 20
+
 // LoadFromFile loads configuration from a YAML file
 func LoadFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -79,6 +559,9 @@ func LoadFromFile(path string) (*Config, error) {
 	if cfg.ScanTimeout == 0 {
 		cfg.ScanTimeout = 30 * time.Second
 	}
+	if cfg.MaxIndexSize == 0 {
+		cfg.MaxIndexSize = DefaultMaxIndexSize
+	}
 	if cfg.MetricsPort == "" {
 		cfg.MetricsPort = "9571"
 	}
@@ -88,17 +571,173 @@ func LoadFromFile(path string) (*Config, error) {
 	if cfg.HTMLPath == "" {
 		cfg.HTMLPath = "/charts"
 	}
+	if cfg.ChartSeries.Overflow == "" {
+		cfg.ChartSeries.Overflow = OverflowTruncate
+	}
+	if cfg.Log.Format == "" {
+		cfg.Log.Format = "text"
+	}
+	if cfg.Log.Level == "" {
+		cfg.Log.Level = "info"
+	}
 
 	// Apply default scan interval to repositories that don't have one
 	for i := range cfg.Repositories {
 		if cfg.Repositories[i].ScanInterval == 0 {
 			cfg.Repositories[i].ScanInterval = cfg.ScanInterval
 		}
+		if cfg.Repositories[i].MaxIndexSize == 0 {
+			cfg.Repositories[i].MaxIndexSize = cfg.MaxIndexSize
+		}
+		if g := cfg.Repositories[i].GenerateIndex; g != nil && g.When == "" {
+			g.When = GenerateWhenMissing
+		}
+	}
+	if n := cfg.Notifications; n != nil {
+		if n.BatchInterval == 0 {
+			n.BatchInterval = time.Minute
+		}
+		if n.MaxRetries == 0 {
+			n.MaxRetries = 3
+		}
+		if n.RetryBackoff == 0 {
+			n.RetryBackoff = time.Second
+		}
+		for i := range n.Subscriptions {
+			if n.Subscriptions[i].Format == "" {
+				n.Subscriptions[i].Format = NotifyFormatGeneric
+			}
+		}
+	}
+	if cfg.OTLP != nil {
+		if cfg.OTLP.Protocol == "" {
+			cfg.OTLP.Protocol = OTLPProtocolGRPC
+		}
+		if cfg.OTLP.Interval == 0 {
+			cfg.OTLP.Interval = time.Minute
+		}
+	}
+	for name, module := range cfg.ProbeModules {
+		if module.MaxIndexSize == 0 {
+			module.MaxIndexSize = cfg.MaxIndexSize
+			cfg.ProbeModules[name] = module
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
 	return &cfg, nil
 }
 
+// Validate checks cross-field constraints that cannot be expressed in YAML
+func (c *Config) Validate() error {
+	names := make(map[string]bool, len(c.Repositories))
+	for _, repo := range c.Repositories {
+		if names[repo.Name] {
+			return fmt.Errorf("duplicate repository name %q", repo.Name)
+		}
+		names[repo.Name] = true
+	}
+
+	switch c.ChartSeries.Overflow {
+	case "", OverflowTruncate, OverflowAggregate:
+	default:
+		return fmt.Errorf("chartSeries.overflow must be %q or %q, got %q", OverflowTruncate, OverflowAggregate, c.ChartSeries.Overflow)
+	}
+	if c.ChartSeries.Limit < 0 {
+		return fmt.Errorf("chartSeries.limit cannot be negative")
+	}
+	for _, repo := range c.Repositories {
+		if repo.MaxChartSeries < 0 {
+			return fmt.Errorf("repository %q: maxChartSeries cannot be negative", repo.Name)
+		}
+	}
+
+	groups := make(map[string]bool, len(c.IndexGroups))
+	for _, group := range c.IndexGroups {
+		switch {
+		case group.Name == "" || strings.ContainsAny(group.Name, "/?#"):
+			return fmt.Errorf("invalid index group name %q", group.Name)
+		case groups[group.Name]:
+			return fmt.Errorf("duplicate index group name %q", group.Name)
+		case len(group.Repositories) == 0:
+			return fmt.Errorf("index group %q has no repositories", group.Name)
+		case group.LatestVersions < 0:
+			return fmt.Errorf("index group %q: latestVersions cannot be negative", group.Name)
+		}
+		groups[group.Name] = true
+		for _, repo := range group.Repositories {
+			if !names[repo] {
+				return fmt.Errorf("index group %q includes unknown repository %q", group.Name, repo)
+			}
+		}
+	}
+
+	for _, repo := range c.Repositories {
+		if repo.Watch && !strings.HasPrefix(repo.URL, "file://") {
+			return fmt.Errorf("repository %q: watch requires a file:// URL", repo.Name)
+		}
+		if err := repo.GenerateIndex.validate(repo); err != nil {
+			return fmt.Errorf("repository %q: %w", repo.Name, err)
+		}
+		if err := repo.TLS.validate(); err != nil {
+			return fmt.Errorf("repository %q: %w", repo.Name, err)
+		}
+		if repo.MirrorOf == "" {
+			continue
+		}
+		if repo.MirrorOf == repo.Name {
+			return fmt.Errorf("repository %q cannot be a mirror of itself", repo.Name)
+		}
+		if !names[repo.MirrorOf] {
+			return fmt.Errorf("repository %q is a mirror of unknown repository %q", repo.Name, repo.MirrorOf)
+		}
+	}
+
+	for name, module := range c.ProbeModules {
+		if err := module.TLS.validate(); err != nil {
+			return fmt.Errorf("probe module %q: %w", name, err)
+		}
+		if err := validateHostPatterns(module.Targets); err != nil {
+			return fmt.Errorf("probe module %q: %w", name, err)
+		}
+		if module.HasCredentials() && len(module.Targets) == 0 {
+			return fmt.Errorf("probe module %q has credentials and must list its targets", name)
+		}
+	}
+	if err := validateHostPatterns(c.ProbeTargets); err != nil {
+		return fmt.Errorf("probeTargets: %w", err)
+	}
+	if err := c.OTLP.validate(); err != nil {
+		return err
+	}
+	if err := c.Log.validate(); err != nil {
+		return err
+	}
+	if err := c.Notifications.validate(names); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LintConfigFor returns the effective lint configuration of a repository
+func (c *Config) LintConfigFor(repo Repository) LintConfig {
+	merged := LintConfig{
+		Severity: make(map[string]string, len(c.Lint.Severity)+len(repo.Lint.Severity)),
+		Suppress: append(append([]string{}, c.Lint.Suppress...), repo.Lint.Suppress...),
+	}
+	for rule, severity := range c.Lint.Severity {
+		merged.Severity[rule] = severity
+	}
+	for rule, severity := range repo.Lint.Severity {
+		merged.Severity[rule] = severity
+	}
+	return merged
+}
+
 // LoadFromEnv loads configuration from environment variables (backward compatibility)
 func LoadFromEnv() (*Config, error) {
 	configFile := os.Getenv("CONFIG_FILE")
@@ -113,6 +752,7 @@ func LoadFromEnv() (*Config, error) {
 	}
 
 	scanInterval := getEnvDuration("SCAN_INTERVAL", 5*time.Minute)
+	maxIndexSize := getEnvInt64("MAX_INDEX_SIZE", DefaultMaxIndexSize)
 
 	cfg := &Config{
 		Repositories: []Repository{
@@ -120,16 +760,43 @@ func LoadFromEnv() (*Config, error) {
 				Name:         "default",
 				URL:          indexURL,
 				ScanInterval: scanInterval,
+				MaxIndexSize: maxIndexSize,
 			},
 		},
-		ScanInterval: scanInterval,
-		ScanTimeout:  getEnvDuration("SCAN_TIMEOUT", 30*time.Second),
-		MetricsPort:  getEnv("METRICS_PORT", "9571"),
-		MetricsPath:  getEnv("METRICS_PATH", "/metrics"),
-		EnableHTML:   getEnvBool("ENABLE_HTML", false),
-		HTMLPath:     getEnv("HTML_PATH", "/charts"),
+		ScanInterval:    scanInterval,
+		MaxIndexSize:    maxIndexSize,
+		ScanTimeout:     getEnvDuration("SCAN_TIMEOUT", 30*time.Second),
+		CollectOnScrape: getEnvBool("COLLECT_ON_SCRAPE", false),
+		MetricsPort:     getEnv("METRICS_PORT", "9571"),
+		MetricsPath:     getEnv("METRICS_PATH", "/metrics"),
+		EnableHTML:      getEnvBool("ENABLE_HTML", false),
+		HTMLPath:        getEnv("HTML_PATH", "/charts"),
+		EnableProbe:     getEnvBool("ENABLE_PROBE", false),
+		Log: LogConfig{
+			Format: getEnv("LOG_FORMAT", "text"),
+			Level:  getEnv("LOG_LEVEL", "info"),
+		},
+		ChartSeries: ChartSeriesConfig{
+			Limit:    int(getEnvInt64("MAX_CHART_SERIES", 0)),
+			Overflow: getEnv("CHART_SERIES_OVERFLOW", OverflowTruncate),
+		},
 	}
 
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		protocol := getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", OTLPProtocolGRPC)
+		if protocol == "http/protobuf" {
+			protocol = OTLPProtocolHTTP
+		}
+		cfg.OTLP = &OTLPConfig{
+			Endpoint: endpoint,
+			Protocol: protocol,
+			Interval: time.Minute,
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -147,6 +814,15 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if d, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfig writes a config file to a temporary directory and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFromFile_LogDefaults(t *testing.T) {
+	cfg, err := LoadFromFile(writeConfig(t, `
+repositories:
+  - name: stable
+    url: https://charts.example.com
+`))
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if cfg.Log.Format != "text" {
+		t.Errorf("Expected the text log format by default, got %q", cfg.Log.Format)
+	}
+	if cfg.Log.Level != "info" {
+		t.Errorf("Expected the info log level by default, got %q", cfg.Log.Level)
+	}
+
+	cfg, err = LoadFromFile(writeConfig(t, `
+log:
+  format: json
+  level: debug
+repositories:
+  - name: stable
+    url: https://charts.example.com
+`))
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if cfg.Log.Format != "json" || cfg.Log.Level != "debug" {
+		t.Errorf("Expected the configured log settings, got %+v", cfg.Log)
+	}
+}
+
+func TestValidate_ProbeModuleTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		module  ProbeModule
+		wantErr bool
+	}{
+		{name: "no credentials", module: ProbeModule{}},
+		{name: "credentials with targets", module: ProbeModule{Auth: &AuthConfig{BearerToken: "token"}, Targets: []string{"*.example.com"}}},
+		{name: "credentials without targets", module: ProbeModule{Auth: &AuthConfig{BearerToken: "token"}}, wantErr: true},
+		{name: "client certificate without targets", module: ProbeModule{TLS: &TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}}, wantErr: true},
+		{name: "invalid target", module: ProbeModule{Targets: []string{"https://charts.example.com"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ProbeModules: map[string]ProbeModule{"test": tt.module}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}